@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mineroot/news/config"
+)
+
+// printConfig writes effective config with the source of every value, secrets are redacted
+func printConfig(_ context.Context, cfg *config.Config, _ []string) error {
+	out := os.Stdout
+	if path := os.Getenv(config.FileEnv); path != "" {
+		_, _ = fmt.Fprintf(out, "config file: %s\n\n", path)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tENV\tVALUE\tSOURCE")
+	for _, s := range cfg.Settings() {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Key, s.Env, s.Value, s.Source)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/health"
+)
+
+// healthcheck requests readiness probe of the running app, so docker image does not need curl or wget
+func healthcheck(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	url := flags.String("url", fmt.Sprintf("http://127.0.0.1:%s%s", cfg.HttpServerPort(), health.ReadyzPath), "readiness probe url")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// readiness checks are limited by ReadinessTimeout on the server side
+	ctx, cancel := context.WithTimeout(ctx, cfg.ReadinessTimeout()+time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, *url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("app is not ready: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/health"
+)
+
+func TestHealthcheck(t *testing.T) {
+	t.Setenv("APP_ENV", "prod")
+	t.Setenv("APP_LOG_LEVEL", "info")
+	t.Setenv("APP_MONGO_URI", "mongodb://localhost:27017")
+	t.Setenv("APP_HTTP_SERVER_PORT", "8080")
+	cfg, err := config.LoadConfig()
+	require.NoError(t, err)
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		assert.Equal(t, health.ReadyzPath, r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	args := []string{"-url", server.URL + health.ReadyzPath}
+
+	require.NoError(t, healthcheck(context.Background(), cfg, args))
+
+	status = http.StatusServiceUnavailable
+	assert.ErrorContains(t, healthcheck(context.Background(), cfg, args), "app is not ready: 503")
+
+	server.Close()
+	assert.Error(t, healthcheck(context.Background(), cfg, args))
+}
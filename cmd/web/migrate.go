@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/db"
+)
+
+// migrate runs "migrate up", "migrate down [steps]" or "migrate status"
+func migrate(ctx context.Context, cfg *config.Config, args []string) error {
+	action, steps, err := parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+	mongoClient, disconnect, err := connectMongo(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	migrator, err := db.NewMigrator(mongoClient, mongoClientConfig(cfg), db.Migrations)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx, steps)
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VERSION\tDESCRIPTION\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.DateTime)
+			}
+			_, _ = fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Description, applied)
+		}
+		return w.Flush()
+	}
+}
+
+// parseMigrateArgs validates args before connecting to mongodb, steps are 1 by default
+func parseMigrateArgs(args []string) (action string, steps int, err error) {
+	switch {
+	case len(args) == 1 && (args[0] == "up" || args[0] == "status"):
+		return args[0], 0, nil
+	case len(args) == 1 && args[0] == "down":
+		return args[0], 1, nil
+	case len(args) == 2 && args[0] == "down":
+		steps, err = strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			return "", 0, fmt.Errorf("invalid number of steps %q", args[1])
+		}
+		return args[0], steps, nil
+	default:
+		return "", 0, errors.New("usage: migrate up|down [steps]|status")
+	}
+}
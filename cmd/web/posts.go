@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/postcache"
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/transfer"
+)
+
+func postsRepository(ctx context.Context, cfg *config.Config) (*posts.Repository, func(), error) {
+	mongoClient, disconnect, err := connectMongo(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	mongoCfg := mongoClientConfig(cfg)
+	return posts.NewRepository(db.GetPostsCollection(mongoClient, mongoCfg), db.GetTrashCollection(mongoClient, mongoCfg)), disconnect, nil
+}
+
+// transferFormat parses -format flag, format is detected by path when flag is empty
+func transferFormat(flagValue, path string) (transfer.Format, error) {
+	if flagValue != "" {
+		return transfer.ParseFormat(flagValue)
+	}
+	if path == "-" {
+		return transfer.FormatNDJSON, nil
+	}
+	return transfer.DetectFormat(path)
+}
+
+// exportPosts writes all posts to file, "-" or directory for markdown format
+func exportPosts(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("posts export", flag.ContinueOnError)
+	formatFlag := flags.String("format", "", "ndjson, csv or markdown, detected by path by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: posts export [-format FORMAT] FILE|DIR")
+	}
+	path := flags.Arg(0)
+	format, err := transferFormat(*formatFlag, path)
+	if err != nil {
+		return err
+	}
+
+	repo, disconnect, err := postsRepository(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	var out io.Writer = os.Stdout
+	if path != "-" && format != transfer.FormatMarkdown {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w, err := transfer.NewWriter(format, out, path)
+	if err != nil {
+		return err
+	}
+
+	exported := 0
+	err = repo.ForEach(ctx, func(post *posts.Post) error {
+		exported++
+		return w.Write(post)
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Int("exported", exported).Str("format", string(format)).Msg("posts are exported")
+	return nil
+}
+
+// importPosts reads posts written by exportPosts, WordPress export or RSS/Atom feed and prints a summary report.
+// Posts with id replace existing ones, so import can be safely repeated, feed items get id from their guid
+func importPosts(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("posts import", flag.ContinueOnError)
+	formatFlag := flags.String("format", "", "ndjson, csv, markdown, wxr or rss, detected by path by default")
+	batchSize := flags.Int("batch", 500, "number of posts in a single bulk write")
+	dryRun := flags.Bool("dry-run", false, "only validate posts")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *batchSize < 1 {
+		return errors.New("usage: posts import [-format FORMAT] [-batch SIZE] [-dry-run] FILE|DIR")
+	}
+	path := flags.Arg(0)
+	format, err := transferFormat(*formatFlag, path)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" && format != transfer.FormatMarkdown {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r, err := transfer.NewReader(format, in, path)
+	if err != nil {
+		return err
+	}
+
+	var repo transfer.BulkSaver
+	var channel postcache.Channel
+	if !*dryRun {
+		mongoClient, disconnect, err := connectMongo(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		defer disconnect()
+		mongoCfg := mongoClientConfig(cfg)
+		repo = posts.NewRepository(db.GetPostsCollection(mongoClient, mongoCfg), db.GetTrashCollection(mongoClient, mongoCfg))
+		if cfg.CacheInvalidation() == "mongo" {
+			channel = postcache.NewMongoChannel(db.GetCacheInvalidationsCollection(mongoClient, mongoCfg))
+		}
+	}
+
+	validate := handlers.NewValidator(cfg.PostTitleMaxLength(), cfg.PostContentMaxLength())
+	importer := transfer.NewImporter(repo)
+	importer.BatchSize = *batchSize
+	importer.DryRun = *dryRun
+	importer.Validate = func(post *posts.Post) error {
+		return handlers.ValidatePost(validate, post.Title, post.Content)
+	}
+
+	report, err := importer.Import(ctx, r)
+	// bulk save bypasses caches of running replicas, they would serve posts from before import until TTL
+	if channel != nil && report.Inserted+report.Replaced > 0 {
+		if err := channel.Publish(ctx, postcache.Invalidation{All: true, Origin: "posts import"}); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("unable to publish cache invalidation")
+		}
+	}
+	for _, e := range report.Errors {
+		fmt.Println(e)
+	}
+	fmt.Println(report)
+	return err
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/db"
+)
+
+// reindex rebuilds full-text index if its definition is changed, search is unavailable until index is created
+func reindex(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	force := flags.Bool("force", false, "rebuild index even if it matches the definition")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	mongoClient, disconnect, err := connectMongo(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	rebuilt, err := db.RebuildTextIndex(ctx, mongoClient, mongoClientConfig(cfg), *force)
+	if err != nil {
+		return err
+	}
+	if !rebuilt {
+		zerolog.Ctx(ctx).Info().Msg("full-text index is up to date")
+		return nil
+	}
+	zerolog.Ctx(ctx).Info().Msg("full-text index is rebuilt")
+	return nil
+}
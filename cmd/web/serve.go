@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/assets"
+	"github.com/mineroot/news/internal/buildinfo"
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/health"
+	"github.com/mineroot/news/internal/live"
+	"github.com/mineroot/news/internal/metrics"
+	"github.com/mineroot/news/internal/middlewares"
+	"github.com/mineroot/news/internal/postcache"
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/ratelimit"
+	"github.com/mineroot/news/internal/route"
+	"github.com/mineroot/news/internal/tracing"
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+// probePaths are excluded from tracing
+var probePaths = []string{"/health", health.LivezPath, health.ReadyzPath}
+
+// serve runs public and admin http servers until ctx is cancelled
+func serve(ctx context.Context, cfg *config.Config, _ []string) error {
+	logger := zerolog.Ctx(ctx)
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingConfig(cfg))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error().Err(err).Msg("unable to flush traces")
+		}
+	}()
+
+	mongoCfg := mongoClientConfig(cfg)
+	mongoClient, disconnect, err := connectMongo(ctx, cfg, metrics.CommandMonitor(), tracing.CommandMonitor())
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	if cfg.MigrateOnStart() {
+		migrator, err := db.NewMigrator(mongoClient, mongoCfg, db.Migrations)
+		if err != nil {
+			return err
+		}
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	}
+
+	repo := posts.NewRepository(db.GetPostsCollection(mongoClient, mongoCfg), db.GetTrashCollection(mongoClient, mongoCfg))
+	repo.SetCounting(posts.Counting{
+		Home:        cfg.PostsCount(),
+		Refresh:     cfg.PostsCountRefresh(),
+		Counters:    db.GetCountersCollection(mongoClient, mongoCfg),
+		SearchLimit: cfg.SearchCountLimit(),
+	})
+	hooks := webhooks.NewRepository(db.GetWebhooksCollection(mongoClient, mongoCfg), db.GetWebhookDeliveriesCollection(mongoClient, mongoCfg))
+	worker := webhooks.NewWorker(hooks, cfg.WebhooksTimeout())
+	worker.Concurrency = cfg.WebhooksConcurrency()
+	worker.MaxAttempts = cfg.WebhooksMaxAttempts()
+	worker.RetryInterval = cfg.WebhooksRetryInterval()
+	worker.MaxRetryInterval = cfg.WebhooksMaxRetryInterval()
+	worker.Retention = cfg.WebhooksRetention()
+	// emitter queues webhook events of posts changes, cache wraps it, so only writes reach it
+	var postRepo postcache.Repository = webhooks.NewEmitter(repo, hooks)
+	var postCache *postcache.Cache
+	if cfg.CacheSize() > 0 {
+		cacheCfg := postcache.Config{Size: cfg.CacheSize(), TTL: cfg.CacheTTL()}
+		if cfg.CacheInvalidation() == "mongo" {
+			cacheCfg.Channel = postcache.NewMongoChannel(db.GetCacheInvalidationsCollection(mongoClient, mongoCfg))
+		}
+		postCache = postcache.New(postRepo, cacheCfg)
+		postRepo = postCache
+	}
+	validation := handlers.NewValidator(cfg.PostTitleMaxLength(), cfg.PostContentMaxLength())
+	checker := health.NewChecker(cfg.ReadinessTimeout(),
+		health.Check{Name: "mongo", Check: db.PingCheck(mongoClient)},
+		health.Check{Name: "text_index", Check: db.TextIndexCheck(db.GetPostsCollection(mongoClient, mongoCfg))},
+	)
+
+	hub := live.NewHub(cfg.LiveBuffer())
+	watcher := live.NewWatcher(db.GetPostsCollection(mongoClient, mongoCfg), db.GetTrashCollection(mongoClient, mongoCfg))
+	watcher.PollInterval = cfg.LivePollInterval()
+
+	var limits ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore() == "mongo" {
+		limits = ratelimit.NewMongoStore(db.GetRateLimitsCollection(mongoClient, mongoCfg))
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.ErrorHandler(logger)
+	e.IPExtractor, err = ipExtractor(cfg.TrustedProxies())
+	if err != nil {
+		return err
+	}
+	e.HideBanner = true
+	e.HidePort = true
+
+	e.Use(middlewares.RequestID(logger))
+	e.Use(otelecho.Middleware(tracing.ServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		return slices.Contains(probePaths, c.Request().URL.Path)
+	})))
+	e.Use(tracing.LoggerMiddleware())
+	e.Use(middlewares.AccessLog(middlewares.AccessLogConfig{
+		SampleEvery: cfg.AccessLogSampleEvery(),
+		SkipPaths:   cfg.AccessLogSkipPaths(),
+	}))
+	e.Use(metrics.Middleware())
+	e.Use(middlewares.SecurityHeaders(securityHeadersConfig(cfg)))
+	e.Use(middlewares.CSRF(middlewares.CSRFConfig{
+		CookieSecure: cfg.CSRFCookieSecure(),
+		SkipRoutes:   append([]string{route.Asset, route.CSPReport}, probePaths...),
+	}))
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		DisableStackAll:   true,
+		DisablePrintStack: true,
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			uri, _ := url.PathUnescape(c.Request().RequestURI)
+			zerolog.Ctx(c.Request().Context()).Error().
+				Str("URI", uri).
+				Err(err).
+				Msg("server panic [recovered]")
+			return err
+		},
+	}))
+
+	// for docker healthcheck
+	e.HEAD("/health", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	probeMethods := []string{http.MethodGet, http.MethodHead}
+	e.Match(probeMethods, health.LivezPath, checker.LivezHandler())
+	e.Match(probeMethods, health.ReadyzPath, checker.ReadyzHandler())
+
+	e.GET(route.Asset, assets.Default.Handler()).Name = route.Asset
+	e.POST(route.CSPReport, handlers.CSPReportHandler(),
+		ratelimit.Middleware(limits, "csp-report", rateLimit(cfg.CSPReportRateLimit()), ratelimit.ByIP),
+	).Name = route.CSPReport
+
+	e.GET(route.Events, handlers.EventsHandler(hub, cfg.LiveHeartbeat())).Name = route.Events
+
+	e.GET(route.ViewHome, handlers.ViewHomeHandler(postRepo, cfg.PageSize())).Name = route.ViewHome
+
+	e.GET(route.ViewPost, handlers.ViewPostHandler(postRepo)).Name = route.ViewPost
+
+	e.GET(route.ViewCreatePostForm, handlers.ViewCreatePostFormHandler()).Name = route.ViewCreatePostForm
+	e.POST(route.CreatePost, handlers.CreatePostHandler(postRepo, validation),
+		ratelimit.Middleware(limits, "create", rateLimit(cfg.CreateRateLimit()), ratelimit.ByUserOrIP),
+	).Name = route.CreatePost
+
+	e.GET(route.ViewUpdatePostForm, handlers.ViewUpdatePostFormHandler(postRepo)).Name = route.ViewUpdatePostForm
+	e.POST(route.UpdatePost, handlers.UpdatePostHandler(postRepo, validation)).Name = route.UpdatePost
+
+	e.POST(route.DeletePost, handlers.DeletePostHandler(postRepo)).Name = route.DeletePost
+	e.POST(route.RestorePost, handlers.RestorePostHandler(postRepo)).Name = route.RestorePost
+
+	e.GET(route.ViewSearch, handlers.ViewSearchHandler(postRepo, cfg.PageSize(), cfg.SearchMinLength()),
+		ratelimit.Middleware(limits, "search", rateLimit(cfg.SearchRateLimit()), ratelimit.ByUserOrIP),
+	).Name = route.ViewSearch
+
+	g, ctx := errgroup.WithContext(ctx)
+	// send webhooks queued by this and other replicas
+	g.Go(func() error {
+		return worker.Run(ctx)
+	})
+	// stream posts changes to live updates clients, they are disconnected when ctx is cancelled,
+	// so http server shutdown does not wait for them
+	g.Go(func() error {
+		if err := hub.Run(ctx, watcher); err != nil {
+			return fmt.Errorf("live updates: %w", err)
+		}
+		return nil
+	})
+	if cfg.PostsCount() == posts.CountCounter {
+		g.Go(func() error {
+			repo.RefreshCounterEvery(ctx)
+			return nil
+		})
+	}
+	// apply posts changes made by other replicas
+	if postCache != nil {
+		g.Go(func() error {
+			if err := postCache.Listen(ctx); err != nil {
+				return fmt.Errorf("posts cache invalidation: %w", err)
+			}
+			return nil
+		})
+	}
+	// run http server
+	g.Go(func() error {
+		addr := fmt.Sprintf(":%s", cfg.HttpServerPort())
+		// ignore http.ErrServerClosed as it's expected after e.Shutdown() call
+		if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("http server: %w", err)
+		}
+		return nil
+	})
+	// gracefully shutdown http server after ctx cancellation
+	g.Go(func() error {
+		<-ctx.Done()
+		// report not ready and give load balancer time to stop sending new requests
+		checker.SetDraining()
+		time.Sleep(cfg.ShutdownDrainDelay())
+		shoutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+		defer cancel()
+		_ = e.Shutdown(shoutdownCtx)
+		return nil
+	})
+
+	// run admin http server, it's not exposed publicly
+	if cfg.AdminServerPort() != "" {
+		admin := echo.New()
+		admin.HTTPErrorHandler = handlers.AdminErrorHandler(logger)
+		admin.HideBanner = true
+		admin.HidePort = true
+		admin.Use(middlewares.RequestID(logger))
+		// csp report endpoint is served by public server only
+		adminHeaders := securityHeadersConfig(cfg)
+		adminHeaders.CSPReportURI = ""
+		admin.Use(middlewares.SecurityHeaders(adminHeaders))
+		// operator's browser may be lured into posting to admin server as well
+		admin.Use(middlewares.CSRF(middlewares.CSRFConfig{
+			CookieSecure: cfg.CSRFCookieSecure(),
+			SkipRoutes:   []string{"/metrics", health.ReadyzPath, "/buildinfo", route.Asset},
+		}))
+		admin.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+		admin.Match(probeMethods, health.ReadyzPath, checker.VerboseReadyzHandler())
+		admin.GET("/buildinfo", func(c echo.Context) error {
+			return c.JSON(http.StatusOK, buildinfo.Get())
+		})
+		admin.GET(route.Asset, assets.Default.Handler()).Name = route.Asset
+		admin.GET(route.ViewWebhookDeliveries, handlers.ViewWebhookDeliveriesHandler(hooks, cfg.WebhooksLogSize())).Name = route.ViewWebhookDeliveries
+		admin.POST(route.RedeliverWebhook, handlers.RedeliverWebhookHandler(hooks)).Name = route.RedeliverWebhook
+
+		g.Go(func() error {
+			addr := fmt.Sprintf(":%s", cfg.AdminServerPort())
+			if err := admin.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("admin http server: %w", err)
+			}
+			return nil
+		})
+		g.Go(func() error {
+			<-ctx.Done()
+			shoutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+			defer cancel()
+			_ = admin.Shutdown(shoutdownCtx)
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+func tracingConfig(cfg *config.Config) tracing.Config {
+	t := cfg.Tracing()
+	return tracing.Config{
+		Exporter:    t.Exporter,
+		Endpoint:    t.Endpoint,
+		File:        t.File,
+		SampleRatio: t.SampleRatio,
+	}
+}
+
+func securityHeadersConfig(cfg *config.Config) middlewares.SecurityHeadersConfig {
+	s := cfg.Security()
+	return middlewares.SecurityHeadersConfig{
+		HSTSMaxAge:            s.HSTSMaxAge,
+		HSTSIncludeSubdomains: s.HSTSIncludeSubdomains,
+		HSTSPreload:           s.HSTSPreload,
+		CSPReportOnly:         s.CSPReportOnly,
+		CSPReportURI:          route.CSPReport,
+		FrameAncestors:        s.FrameAncestors,
+		ReferrerPolicy:        s.ReferrerPolicy,
+		PermissionsPolicy:     s.PermissionsPolicy,
+	}
+}
+
+// ipExtractor takes client ip from X-Forwarded-For set by trusted proxies, without them it's the remote address
+func ipExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range trustedProxies {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+		}
+		options = append(options, echo.TrustIPRange(network))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+func rateLimit(limit config.RateLimit) ratelimit.Limit {
+	return ratelimit.Limit{Requests: limit.Requests, Period: limit.Period}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/site"
+)
+
+// exportSite renders home pages, post pages and RSS feed into directory
+func exportSite(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("site export", flag.ContinueOnError)
+	baseURL := flags.String("base-url", "", "public url of the site, makes feed links absolute")
+	feedSize := flags.Int("feed-size", site.DefaultFeedSize, "number of latest posts in the feed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *feedSize < 0 {
+		return errors.New("usage: site export [-base-url URL] [-feed-size N] DIR")
+	}
+
+	repo, disconnect, err := postsRepository(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	exporter := site.NewExporter(repo, cfg.PageSize())
+	exporter.BaseURL = *baseURL
+	exporter.FeedSize = *feedSize
+	report, err := exporter.Export(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Int("pages", report.Pages).Int("posts", report.Posts).Str("dir", flags.Arg(0)).Msg("site is exported")
+	return nil
+}
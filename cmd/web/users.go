@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/users"
+)
+
+func usersRepository(ctx context.Context, cfg *config.Config) (*users.Repository, func(), error) {
+	mongoClient, disconnect, err := connectMongo(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return users.NewRepository(db.GetUsersCollection(mongoClient, mongoClientConfig(cfg))), disconnect, nil
+}
+
+// createUser reads password from the first line of stdin, so it never appears in shell history
+func createUser(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("users create", flag.ContinueOnError)
+	email := flags.String("email", "", "user email")
+	role := flags.String("role", string(users.RoleEditor), "user role")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return errors.New("password must be passed to stdin")
+	}
+	user, err := users.New(*email, strings.TrimRight(password, "\r\n"), users.Role(*role))
+	if err != nil {
+		return err
+	}
+
+	repo, disconnect, err := usersRepository(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	if _, err := repo.Create(ctx, user); err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Str("email", user.Email).Str("role", string(user.Role)).Msg("user is created")
+	return nil
+}
+
+func setUserRole(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: users set-role EMAIL ROLE")
+	}
+	email, err := users.NormalizeEmail(args[0])
+	if err != nil {
+		return err
+	}
+	role, err := users.ParseRole(args[1])
+	if err != nil {
+		return err
+	}
+
+	repo, disconnect, err := usersRepository(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	if _, err := repo.SetRole(ctx, email, role); err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Str("email", email).Str("role", string(role)).Msg("user role is changed")
+	return nil
+}
@@ -2,31 +2,59 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
-	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/go-playground/validator/v10"
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/sync/errgroup"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 
 	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/buildinfo"
 	"github.com/mineroot/news/internal/db"
-	"github.com/mineroot/news/internal/handlers"
-	"github.com/mineroot/news/internal/posts"
-	"github.com/mineroot/news/internal/route"
 )
 
+// command is a subcommand, name may consist of several words, e.g. "posts import"
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, cfg *config.Config, args []string) error
+}
+
+var commands = []command{
+	{name: "serve", usage: "run http servers, default command", run: serve},
+	{name: "config print", usage: "print effective config and source of every value", run: printConfig},
+	{name: "migrate", usage: "migrate up|down [steps]|status", run: migrate},
+	{name: "posts import", usage: "posts import [-format FORMAT] [-batch SIZE] [-dry-run] FILE|DIR, - reads stdin", run: importPosts},
+	{name: "posts export", usage: "posts export [-format FORMAT] FILE|DIR, - writes stdout", run: exportPosts},
+	{name: "site export", usage: "site export [-base-url URL] [-feed-size N] DIR, render static html site", run: exportSite},
+	{name: "reindex", usage: "reindex [-force], rebuild full-text search index if its definition is changed", run: reindex},
+	{name: "users create", usage: "users create -email EMAIL [-role ROLE], password is read from stdin", run: createUser},
+	{name: "users set-role", usage: "users set-role EMAIL ROLE", run: setUserRole},
+	{name: "webhooks add", usage: "webhooks add -url URL [-events EVENT,...], secret is generated and printed", run: addWebhook},
+	{name: "webhooks list", usage: "webhooks list", run: listWebhooks},
+	{name: "webhooks remove", usage: "webhooks remove ID", run: removeWebhook},
+	{name: "healthcheck", usage: "healthcheck [-url URL], exits with non-zero code if app is not ready", run: healthcheck},
+}
+
 func main() {
+	if len(os.Args) == 2 && slices.Contains([]string{"help", "-h", "--help"}, os.Args[1]) {
+		printUsage(os.Stdout)
+		return
+	}
+	cmd, args, ok := findCommand(os.Args[1:])
+	if !ok {
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+
 	// load config
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -40,96 +68,89 @@ func main() {
 		out = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.DateTime, NoColor: cfg.IsProd()}
 	}
 	logger := log.Output(out).With().Caller().Logger().Level(cfg.LogLevel())
-	logger.Info().
-		Stringer("config", cfg).
-		Send()
+	if cmd.name == "serve" {
+		logger.Info().
+			Stringer("config", cfg).
+			Interface("build", buildinfo.Get()).
+			Send()
+	}
 	ctx := logger.WithContext(context.Background())
 
 	// respect os signals
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	// run app
-	if err := run(ctx, cfg); err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("unexpected exit")
+	// run command
+	if err := cmd.run(ctx, cfg, args); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("command", cmd.name).Msg("unexpected exit")
 		os.Exit(1)
 	}
 	logger.Debug().Msg("successfully exited")
 }
 
-func run(ctx context.Context, cfg *config.Config) error {
-	logger := zerolog.Ctx(ctx)
+// findCommand matches args against command names, "serve" is used when there are no args
+func findCommand(args []string) (command, []string, bool) {
+	if len(args) == 0 {
+		return commands[0], nil, true
+	}
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && slices.Equal(args[:len(words)], words) {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
 
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "usage: web [command] [args]")
+	_, _ = fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// connectMongo connects to mongodb, returned function disconnects within shutdown timeout
+func connectMongo(ctx context.Context, cfg *config.Config, monitors ...*event.CommandMonitor) (*mongo.Client, func(), error) {
+	logger := zerolog.Ctx(ctx)
 	logger.Debug().Msg("connecting to mongodb")
-	mongoClient, err := db.CreateMongoClient(ctx, cfg.MongoUri())
+	mongoClient, err := db.CreateMongoClient(ctx, mongoClientConfig(cfg), monitors...)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	logger.Debug().Msg("mongodb is up")
-	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return mongoClient, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
 		defer cancel()
 		_ = mongoClient.Disconnect(ctx)
-	}()
-
-	postRepo := posts.NewRepository(db.GetPostsCollection(mongoClient))
-	validation := validator.New(validator.WithRequiredStructEnabled())
-
-	e := echo.New()
-	e.HTTPErrorHandler = handlers.ErrorHandler(logger)
-	e.HideBanner = true
-	e.HidePort = true
-
-	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
-		DisableStackAll:   true,
-		DisablePrintStack: true,
-		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
-			uri, _ := url.PathUnescape(c.Request().RequestURI)
-			logger.Error().
-				Str("URI", uri).
-				Err(err).
-				Msg("server panic [recovered]")
-			return err
-		},
-	}))
-
-	// for docker healthcheck
-	e.HEAD("/health", func(c echo.Context) error {
-		return c.NoContent(http.StatusOK)
-	})
-
-	e.GET(route.ViewHome, handlers.ViewHomeHandler(postRepo)).Name = route.ViewHome
-
-	e.GET(route.ViewPost, handlers.ViewPostHandler(postRepo)).Name = route.ViewPost
-
-	e.GET(route.ViewCreatePostForm, handlers.ViewCreatePostFormHandler()).Name = route.ViewCreatePostForm
-	e.POST(route.CreatePost, handlers.CreatePostHandler(postRepo, validation)).Name = route.CreatePost
-
-	e.GET(route.ViewUpdatePostForm, handlers.ViewUpdatePostFormHandler(postRepo)).Name = route.ViewUpdatePostForm
-	e.POST(route.UpdatePost, handlers.UpdatePostHandler(postRepo, validation)).Name = route.UpdatePost
-
-	e.POST(route.DeletePost, handlers.DeletePostHandler(postRepo)).Name = route.DeletePost
-
-	e.GET(route.ViewSearch, handlers.ViewSearchHandler(postRepo)).Name = route.ViewSearch
-
-	g, ctx := errgroup.WithContext(ctx)
-	// run http server
-	g.Go(func() error {
-		addr := fmt.Sprintf(":%s", cfg.HttpServerPort())
-		// ignore http.ErrServerClosed as it's expected after e.Shutdown() call
-		if err := e.Start(addr); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			return fmt.Errorf("http server: %w", err)
-		}
-		return nil
-	})
-	// gracefully shutdown http server after ctx cancellation
-	g.Go(func() error {
-		<-ctx.Done()
-		shoutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		_ = e.Shutdown(shoutdownCtx)
-		return nil
-	})
+	}, nil
+}
 
-	return g.Wait()
+func mongoClientConfig(cfg *config.Config) db.ClientConfig {
+	m := cfg.Mongo()
+	return db.ClientConfig{
+		URI:             m.URI.Value(),
+		MaxPoolSize:     m.MaxPoolSize,
+		MinPoolSize:     m.MinPoolSize,
+		MaxConnIdleTime: m.MaxConnIdleTime,
+
+		ConnectTimeout:         m.ConnectTimeout,
+		ServerSelectionTimeout: m.ServerSelectionTimeout,
+		OperationTimeout:       m.OperationTimeout,
+
+		ReadConcern:  m.ReadConcern,
+		WriteConcern: m.WriteConcern,
+
+		TLSCAFile:   m.TLSCAFile,
+		TLSCertFile: m.TLSCertFile,
+		TLSKeyFile:  m.TLSKeyFile,
+
+		Compressors: m.Compressors,
+		AppName:     m.AppName,
+
+		Database:        m.Database,
+		PostsCollection: m.PostsCollection,
+		TrashCollection: m.TrashCollection,
+		UsersCollection: m.UsersCollection,
+	}
 }
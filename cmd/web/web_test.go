@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+func TestFindCommand(t *testing.T) {
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedName string
+		expectedArgs []string
+		expectedOk   bool
+	}{
+		{"default", nil, "serve", nil, true},
+		{"single word", []string{"migrate", "down", "2"}, "migrate", []string{"down", "2"}, true},
+		{"several words", []string{"posts", "import", "-dry-run", "posts.csv"}, "posts import", []string{"-dry-run", "posts.csv"}, true},
+		{"incomplete name", []string{"posts"}, "", nil, false},
+		{"unknown", []string{"deploy"}, "", nil, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, args, ok := findCommand(tc.args)
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedName, cmd.name)
+			assert.Equal(t, tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestPrintUsage(t *testing.T) {
+	var buf bytes.Buffer
+	printUsage(&buf)
+	for _, cmd := range commands {
+		assert.Contains(t, buf.String(), cmd.name)
+	}
+}
+
+func TestParseMigrateArgs(t *testing.T) {
+	testCases := []struct {
+		args           []string
+		expectedAction string
+		expectedSteps  int
+		expectedErr    string
+	}{
+		{[]string{"up"}, "up", 0, ""},
+		{[]string{"status"}, "status", 0, ""},
+		{[]string{"down"}, "down", 1, ""},
+		{[]string{"down", "3"}, "down", 3, ""},
+		{[]string{"down", "0"}, "", 0, `invalid number of steps "0"`},
+		{[]string{"down", "all"}, "", 0, `invalid number of steps "all"`},
+		{[]string{"up", "1"}, "", 0, "usage: migrate"},
+		{nil, "", 0, "usage: migrate"},
+	}
+	for _, tc := range testCases {
+		action, steps, err := parseMigrateArgs(tc.args)
+		if tc.expectedErr != "" {
+			assert.ErrorContains(t, err, tc.expectedErr, tc.args)
+			continue
+		}
+		require.NoError(t, err, tc.args)
+		assert.Equal(t, tc.expectedAction, action)
+		assert.Equal(t, tc.expectedSteps, steps)
+	}
+}
+
+// TestCommands_InvalidArgs checks that args are validated before connecting to mongodb, so config is not needed
+func TestCommands_InvalidArgs(t *testing.T) {
+	testCases := []struct {
+		args        []string
+		expectedErr string
+	}{
+		{[]string{"migrate"}, "usage: migrate"},
+		{[]string{"reindex", "-rebuild"}, "flag provided but not defined"},
+		{[]string{"posts", "export"}, "usage: posts export"},
+		{[]string{"users", "set-role", "admin@example.com"}, "usage: users set-role"},
+		{[]string{"users", "set-role", "admin@example.com", "root"}, "role"},
+		{[]string{"webhooks", "add", "-url", "ftp://example.com"}, "url must be absolute http(s) url"},
+		{[]string{"webhooks", "add", "-url", "https://example.com", "-events", "post.published"}, "unknown event"},
+		{[]string{"webhooks", "remove"}, "usage: webhooks remove"},
+		{[]string{"webhooks", "remove", "1"}, webhooks.ErrNotFound.Error()},
+	}
+	for _, tc := range testCases {
+		cmd, args, ok := findCommand(tc.args)
+		require.True(t, ok, tc.args)
+		assert.ErrorContains(t, cmd.run(context.Background(), nil, args), tc.expectedErr, tc.args)
+	}
+}
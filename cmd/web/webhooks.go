@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/config"
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+func webhooksRepository(ctx context.Context, cfg *config.Config) (*webhooks.Repository, func(), error) {
+	mongoClient, disconnect, err := connectMongo(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	mongoCfg := mongoClientConfig(cfg)
+	return webhooks.NewRepository(db.GetWebhooksCollection(mongoClient, mongoCfg), db.GetWebhookDeliveriesCollection(mongoClient, mongoCfg)), disconnect, nil
+}
+
+// addWebhook prints secret to stdout only, it can't be shown again
+func addWebhook(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("webhooks add", flag.ContinueOnError)
+	url := flags.String("url", "", "receiver url")
+	eventsFlag := flags.String("events", "", "comma separated events, all events by default: "+strings.Join(webhooks.Events, ","))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var events []string
+	if *eventsFlag != "" {
+		events = strings.Split(*eventsFlag, ",")
+	}
+	sub, err := webhooks.NewSubscription(*url, rand.Text(), events)
+	if err != nil {
+		return err
+	}
+
+	repo, disconnect, err := webhooksRepository(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	if _, err := repo.CreateSubscription(ctx, sub); err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Str("id", sub.ID.Hex()).Str("url", sub.URL).Msg("webhook is added")
+	fmt.Printf("id: %s\nsecret: %s\n", sub.ID.Hex(), sub.Secret)
+	return nil
+}
+
+func listWebhooks(ctx context.Context, cfg *config.Config, _ []string) error {
+	repo, disconnect, err := webhooksRepository(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	subs, err := repo.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		events := "all"
+		if len(sub.Events) > 0 {
+			events = strings.Join(sub.Events, ",")
+		}
+		fmt.Printf("%s\t%s\t%s\n", sub.ID.Hex(), sub.URL, events)
+	}
+	return nil
+}
+
+func removeWebhook(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: webhooks remove ID")
+	}
+	id, err := bson.ObjectIDFromHex(args[0])
+	if err != nil {
+		return webhooks.ErrNotFound
+	}
+
+	repo, disconnect, err := webhooksRepository(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	if err := repo.DeleteSubscription(ctx, id); err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Str("id", id.Hex()).Msg("webhook is removed")
+	return nil
+}
@@ -2,21 +2,171 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/connstring"
 )
 
+// FileEnv points to optional YAML or TOML config file, env variables take precedence over it
+const FileEnv = "APP_CONFIG_FILE"
+
 type config struct {
-	Env            string `env:"APP_ENV" validate:"required,oneof=dev prod"`
-	LogLevel       string `env:"APP_LOG_LEVEL" validate:"required,oneof=debug info warning error"`
-	MongoURI       string `env:"APP_MONGO_URI" validate:"required"`
-	HttpServerPort string `env:"APP_HTTP_SERVER_PORT" validate:"required,alphanum"`
+	Env      string `yaml:"env" toml:"env" env:"APP_ENV" validate:"required,oneof=dev prod"`
+	LogLevel string `yaml:"log_level" toml:"log_level" env:"APP_LOG_LEVEL" validate:"required,oneof=debug info warning error"`
+
+	HTTP      httpConfig      `yaml:"http" toml:"http"`
+	Mongo     MongoConfig     `yaml:"mongo" toml:"mongo"`
+	Posts     postsConfig     `yaml:"posts" toml:"posts"`
+	Security  SecurityConfig  `yaml:"security" toml:"security"`
+	RateLimit rateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Cache     cacheConfig     `yaml:"cache" toml:"cache"`
+	Live      liveConfig      `yaml:"live" toml:"live"`
+	Webhooks  webhooksConfig  `yaml:"webhooks" toml:"webhooks"`
+	AccessLog accessLogConfig `yaml:"access_log" toml:"access_log"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	Shutdown  shutdownConfig  `yaml:"shutdown" toml:"shutdown"`
+}
+
+type httpConfig struct {
+	ServerPort string `yaml:"server_port" toml:"server_port" env:"APP_HTTP_SERVER_PORT" validate:"required,alphanum"`
+	// AdminServerPort serves /metrics, verbose /readyz, /buildinfo and webhook deliveries, it must not be exposed publicly,
+	// empty disables admin server
+	AdminServerPort  string        `yaml:"admin_server_port" toml:"admin_server_port" env:"APP_ADMIN_SERVER_PORT" validate:"omitempty,alphanum,nefield=ServerPort"`
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" toml:"readiness_timeout" env:"APP_HTTP_READINESS_TIMEOUT" validate:"min=1ms"`
+	// TrustedProxies are CIDRs of reverse proxies, client ip is taken from X-Forwarded-For only behind them
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"APP_HTTP_TRUSTED_PROXIES" validate:"dive,cidr"`
+}
+
+// MongoConfig is mongodb client options, they are applied over URI options,
+// zero values keep URI options or driver defaults
+type MongoConfig struct {
+	URI             Secret        `yaml:"uri" toml:"uri" env:"APP_MONGO_URI" validate:"required,mongodb_uri"`
+	MaxPoolSize     uint64        `yaml:"max_pool_size" toml:"max_pool_size" env:"APP_MONGO_MAX_POOL_SIZE"`
+	MinPoolSize     uint64        `yaml:"min_pool_size" toml:"min_pool_size" env:"APP_MONGO_MIN_POOL_SIZE" validate:"min_pool_size"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time" toml:"max_conn_idle_time" env:"APP_MONGO_MAX_CONN_IDLE_TIME" validate:"min=0"`
+
+	ConnectTimeout         time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"APP_MONGO_CONNECT_TIMEOUT" validate:"min=0"`
+	ServerSelectionTimeout time.Duration `yaml:"server_selection_timeout" toml:"server_selection_timeout" env:"APP_MONGO_SERVER_SELECTION_TIMEOUT" validate:"min=0"`
+	// OperationTimeout replaces socket timeout, which is removed in mongodb driver v2
+	OperationTimeout time.Duration `yaml:"operation_timeout" toml:"operation_timeout" env:"APP_MONGO_OPERATION_TIMEOUT" validate:"min=0"`
+
+	ReadConcern  string `yaml:"read_concern" toml:"read_concern" env:"APP_MONGO_READ_CONCERN" validate:"omitempty,oneof=local available majority linearizable snapshot"`
+	WriteConcern string `yaml:"write_concern" toml:"write_concern" env:"APP_MONGO_WRITE_CONCERN" validate:"omitempty,write_concern"`
+
+	TLSCAFile   string `yaml:"tls_ca_file" toml:"tls_ca_file" env:"APP_MONGO_TLS_CA_FILE" validate:"omitempty,file"`
+	TLSCertFile string `yaml:"tls_cert_file" toml:"tls_cert_file" env:"APP_MONGO_TLS_CERT_FILE" validate:"omitempty,file"`
+	// TLSKeyFile may be omitted when private key is in TLSCertFile
+	TLSKeyFile string `yaml:"tls_key_file" toml:"tls_key_file" env:"APP_MONGO_TLS_KEY_FILE" validate:"excluded_without=TLSCertFile,omitempty,file"`
+
+	Compressors []string `yaml:"compressors" toml:"compressors" env:"APP_MONGO_COMPRESSORS" validate:"dive,oneof=snappy zlib zstd"`
+	AppName     string   `yaml:"app_name" toml:"app_name" env:"APP_MONGO_APP_NAME" validate:"max=128"`
+
+	Database        string `yaml:"database" toml:"database" env:"APP_MONGO_DATABASE" validate:"required,excludesall=/\\. \"$"`
+	PostsCollection string `yaml:"posts_collection" toml:"posts_collection" env:"APP_MONGO_POSTS_COLLECTION" validate:"required,excludes=$"`
+	TrashCollection string `yaml:"trash_collection" toml:"trash_collection" env:"APP_MONGO_TRASH_COLLECTION" validate:"required,excludes=$,nefield=PostsCollection"`
+	UsersCollection string `yaml:"users_collection" toml:"users_collection" env:"APP_MONGO_USERS_COLLECTION" validate:"required,excludes=$,nefield=PostsCollection,nefield=TrashCollection"`
+
+	// MigrateOnStart applies pending migrations before http server starts
+	MigrateOnStart bool `yaml:"migrate_on_start" toml:"migrate_on_start" env:"APP_MONGO_MIGRATE_ON_START"`
+}
+
+type postsConfig struct {
+	PageSize       int `yaml:"page_size" toml:"page_size" env:"APP_POSTS_PAGE_SIZE" validate:"min=1,max=100"`
+	TitleMaxLength int `yaml:"title_max_length" toml:"title_max_length" env:"APP_POSTS_TITLE_MAX_LENGTH" validate:"min=1,max=1000"`
+	// ContentMaxLength is limited, so a post always fits into 16MB mongodb document
+	ContentMaxLength int `yaml:"content_max_length" toml:"content_max_length" env:"APP_POSTS_CONTENT_MAX_LENGTH" validate:"min=1,max=1000000"`
+	SearchMinLength  int `yaml:"search_min_length" toml:"search_min_length" env:"APP_POSTS_SEARCH_MIN_LENGTH" validate:"min=1"`
+	// Count is how total of home page is counted: "exact", "estimated" from collection metadata or "counter" document
+	Count string `yaml:"count" toml:"count" env:"APP_POSTS_COUNT" validate:"oneof=exact estimated counter"`
+	// CountRefresh is how long estimated count is reused and how often counter document is refreshed
+	CountRefresh time.Duration `yaml:"count_refresh" toml:"count_refresh" env:"APP_POSTS_COUNT_REFRESH" validate:"min=1s"`
+	// SearchCountLimit stops counting search results, e.g. "1000+ results", 0 counts all of them
+	SearchCountLimit int `yaml:"search_count_limit" toml:"search_count_limit" env:"APP_POSTS_SEARCH_COUNT_LIMIT" validate:"min=0"`
+}
+
+// SecurityConfig is security headers and CSRF cookie options
+type SecurityConfig struct {
+	// HSTSMaxAge enables Strict-Transport-Security, set it only when app is served over https
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" toml:"hsts_max_age" env:"APP_SECURITY_HSTS_MAX_AGE" validate:"min=0"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" toml:"hsts_include_subdomains" env:"APP_SECURITY_HSTS_INCLUDE_SUBDOMAINS"`
+	HSTSPreload           bool          `yaml:"hsts_preload" toml:"hsts_preload" env:"APP_SECURITY_HSTS_PRELOAD"`
+	// CSPReportOnly only reports Content-Security-Policy violations to /csp-report, e.g. to try policy changes
+	CSPReportOnly     bool     `yaml:"csp_report_only" toml:"csp_report_only" env:"APP_SECURITY_CSP_REPORT_ONLY"`
+	FrameAncestors    []string `yaml:"frame_ancestors" toml:"frame_ancestors" env:"APP_SECURITY_FRAME_ANCESTORS" validate:"min=1"`
+	ReferrerPolicy    string   `yaml:"referrer_policy" toml:"referrer_policy" env:"APP_SECURITY_REFERRER_POLICY" validate:"omitempty,oneof=no-referrer no-referrer-when-downgrade origin origin-when-cross-origin same-origin strict-origin strict-origin-when-cross-origin unsafe-url"`
+	PermissionsPolicy string   `yaml:"permissions_policy" toml:"permissions_policy" env:"APP_SECURITY_PERMISSIONS_POLICY"`
+	// CSRFCookieSecure sends CSRF cookie over https only
+	CSRFCookieSecure bool `yaml:"csrf_cookie_secure" toml:"csrf_cookie_secure" env:"APP_SECURITY_CSRF_COOKIE_SECURE"`
+}
+
+type rateLimitConfig struct {
+	// Store is "memory" for a single replica or "mongo" to share limits between replicas
+	Store string `yaml:"store" toml:"store" env:"APP_RATE_LIMIT_STORE" validate:"oneof=memory mongo"`
+	// limits are REQUESTS/PERIOD, e.g. 30/1m, empty disables limit
+	Search string `yaml:"search" toml:"search" env:"APP_RATE_LIMIT_SEARCH" validate:"omitempty,rate_limit"`
+	Create string `yaml:"create" toml:"create" env:"APP_RATE_LIMIT_CREATE" validate:"omitempty,rate_limit"`
+	Login  string `yaml:"login" toml:"login" env:"APP_RATE_LIMIT_LOGIN" validate:"omitempty,rate_limit"`
+	// CSPReport limits browser reports of csp violations
+	CSPReport string `yaml:"csp_report" toml:"csp_report" env:"APP_RATE_LIMIT_CSP_REPORT" validate:"omitempty,rate_limit"`
+}
+
+type cacheConfig struct {
+	// Size is max number of cached listing pages and posts each, 0 disables cache
+	Size int           `yaml:"size" toml:"size" env:"APP_CACHE_SIZE" validate:"min=0"`
+	TTL  time.Duration `yaml:"ttl" toml:"ttl" env:"APP_CACHE_TTL" validate:"min=1ms"`
+	// Invalidation is "none" for a single replica or "mongo" to invalidate caches of all replicas on change
+	Invalidation string `yaml:"invalidation" toml:"invalidation" env:"APP_CACHE_INVALIDATION" validate:"oneof=none mongo"`
+}
+
+type liveConfig struct {
+	// Buffer is how many events are queued for a client, slower clients are disconnected
+	Buffer    int           `yaml:"buffer" toml:"buffer" env:"APP_LIVE_BUFFER" validate:"min=1"`
+	Heartbeat time.Duration `yaml:"heartbeat" toml:"heartbeat" env:"APP_LIVE_HEARTBEAT" validate:"min=1s"`
+	// PollInterval is used instead of change streams, which are not supported by standalone mongodb
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"APP_LIVE_POLL_INTERVAL" validate:"min=100ms"`
+}
+
+type webhooksConfig struct {
+	Timeout     time.Duration `yaml:"timeout" toml:"timeout" env:"APP_WEBHOOKS_TIMEOUT" validate:"min=1s"`
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts" env:"APP_WEBHOOKS_MAX_ATTEMPTS" validate:"min=1"`
+	// RetryInterval is a pause before the first retry, the next ones grow exponentially up to MaxRetryInterval
+	RetryInterval    time.Duration `yaml:"retry_interval" toml:"retry_interval" env:"APP_WEBHOOKS_RETRY_INTERVAL" validate:"min=1s"`
+	MaxRetryInterval time.Duration `yaml:"max_retry_interval" toml:"max_retry_interval" env:"APP_WEBHOOKS_MAX_RETRY_INTERVAL" validate:"gtefield=RetryInterval"`
+	Concurrency      int           `yaml:"concurrency" toml:"concurrency" env:"APP_WEBHOOKS_CONCURRENCY" validate:"min=1"`
+	// Retention is how long delivered ones are kept in the delivery log, dead ones are kept until redelivered
+	Retention time.Duration `yaml:"retention" toml:"retention" env:"APP_WEBHOOKS_RETENTION" validate:"min=1m"`
+	// LogSize is number of deliveries shown by delivery log page
+	LogSize int `yaml:"log_size" toml:"log_size" env:"APP_WEBHOOKS_LOG_SIZE" validate:"min=1,max=1000"`
+}
+
+type accessLogConfig struct {
+	SampleEvery int      `yaml:"sample_every" toml:"sample_every" env:"APP_ACCESS_LOG_SAMPLE_EVERY" validate:"min=1"`
+	SkipPaths   []string `yaml:"skip_paths" toml:"skip_paths" env:"APP_ACCESS_LOG_SKIP_PATHS" validate:"dive,startswith=/"`
+}
+
+// TracingConfig is OpenTelemetry exporter options, Exporter is "none", "otlp", "stdout" or "file"
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter" env:"APP_TRACING_EXPORTER" validate:"oneof=none otlp stdout file"`
+	Endpoint    string  `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"APP_TRACING_OTLP_ENDPOINT" validate:"required_if=Exporter otlp,omitempty,url"`
+	File        string  `yaml:"file" toml:"file" env:"APP_TRACING_FILE" validate:"required_if=Exporter file"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"APP_TRACING_SAMPLE_RATIO" validate:"min=0,max=1"`
+}
+
+type shutdownConfig struct {
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"APP_SHUTDOWN_TIMEOUT" validate:"min=1s"`
+	// DrainDelay is how long /readyz reports "draining" before http server stops accepting connections,
+	// it should be longer than readiness probe period of load balancer
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay" env:"APP_SHUTDOWN_DRAIN_DELAY" validate:"min=0"`
 }
 
 type Config struct {
-	config config
+	config  config
+	sources map[string]Source
 }
 
 func (c *Config) String() string {
@@ -28,11 +178,181 @@ func (c *Config) IsProd() bool {
 }
 
 func (c *Config) MongoUri() string {
-	return c.config.MongoURI
+	return c.config.Mongo.URI.Value()
+}
+
+func (c *Config) Mongo() MongoConfig {
+	return c.config.Mongo
+}
+
+func (c *Config) MigrateOnStart() bool {
+	return c.config.Mongo.MigrateOnStart
 }
 
 func (c *Config) HttpServerPort() string {
-	return c.config.HttpServerPort
+	return c.config.HTTP.ServerPort
+}
+
+func (c *Config) AdminServerPort() string {
+	return c.config.HTTP.AdminServerPort
+}
+
+// ReadinessTimeout limits all readiness checks together
+func (c *Config) ReadinessTimeout() time.Duration {
+	return c.config.HTTP.ReadinessTimeout
+}
+
+func (c *Config) PageSize() int {
+	return c.config.Posts.PageSize
+}
+
+func (c *Config) PostTitleMaxLength() int {
+	return c.config.Posts.TitleMaxLength
+}
+
+func (c *Config) PostContentMaxLength() int {
+	return c.config.Posts.ContentMaxLength
+}
+
+// SearchMinLength is minimal search query length in characters
+func (c *Config) SearchMinLength() int {
+	return c.config.Posts.SearchMinLength
+}
+
+// PostsCount is strategy of counting posts on home page: "exact", "estimated" or "counter"
+func (c *Config) PostsCount() string {
+	return c.config.Posts.Count
+}
+
+func (c *Config) PostsCountRefresh() time.Duration {
+	return c.config.Posts.CountRefresh
+}
+
+// SearchCountLimit stops counting search results, 0 counts all of them
+func (c *Config) SearchCountLimit() int {
+	return c.config.Posts.SearchCountLimit
+}
+
+// ShutdownTimeout limits graceful shutdown of every component: http servers, mongodb client, tracing exporter
+func (c *Config) ShutdownTimeout() time.Duration {
+	return c.config.Shutdown.Timeout
+}
+
+func (c *Config) ShutdownDrainDelay() time.Duration {
+	return c.config.Shutdown.DrainDelay
+}
+
+func (c *Config) Security() SecurityConfig {
+	return c.config.Security
+}
+
+func (c *Config) CSRFCookieSecure() bool {
+	return c.config.Security.CSRFCookieSecure
+}
+
+// TrustedProxies are CIDRs of reverse proxies allowed to set X-Forwarded-For
+func (c *Config) TrustedProxies() []string {
+	return c.config.HTTP.TrustedProxies
+}
+
+func (c *Config) RateLimitStore() string {
+	return c.config.RateLimit.Store
+}
+
+// SearchRateLimit limits search requests per client
+func (c *Config) SearchRateLimit() RateLimit {
+	limit, _ := parseRateLimit(c.config.RateLimit.Search)
+	return limit
+}
+
+// CreateRateLimit limits post creation per client
+func (c *Config) CreateRateLimit() RateLimit {
+	limit, _ := parseRateLimit(c.config.RateLimit.Create)
+	return limit
+}
+
+// LoginRateLimit limits login attempts per client ip, login requests are not authenticated yet
+func (c *Config) LoginRateLimit() RateLimit {
+	limit, _ := parseRateLimit(c.config.RateLimit.Login)
+	return limit
+}
+
+// CSPReportRateLimit limits csp violation reports per client ip
+func (c *Config) CSPReportRateLimit() RateLimit {
+	limit, _ := parseRateLimit(c.config.RateLimit.CSPReport)
+	return limit
+}
+
+// CacheSize is max number of cached listing pages and posts each, 0 disables posts cache
+func (c *Config) CacheSize() int {
+	return c.config.Cache.Size
+}
+
+func (c *Config) CacheTTL() time.Duration {
+	return c.config.Cache.TTL
+}
+
+func (c *Config) CacheInvalidation() string {
+	return c.config.Cache.Invalidation
+}
+
+// LiveBuffer is how many live updates are queued for a client
+func (c *Config) LiveBuffer() int {
+	return c.config.Live.Buffer
+}
+
+func (c *Config) LiveHeartbeat() time.Duration {
+	return c.config.Live.Heartbeat
+}
+
+// LivePollInterval is how often posts are polled when mongodb has no change streams
+func (c *Config) LivePollInterval() time.Duration {
+	return c.config.Live.PollInterval
+}
+
+// WebhooksTimeout limits a single delivery attempt
+func (c *Config) WebhooksTimeout() time.Duration {
+	return c.config.Webhooks.Timeout
+}
+
+// WebhooksMaxAttempts is number of attempts before delivery is moved to dead letters
+func (c *Config) WebhooksMaxAttempts() int {
+	return c.config.Webhooks.MaxAttempts
+}
+
+func (c *Config) WebhooksRetryInterval() time.Duration {
+	return c.config.Webhooks.RetryInterval
+}
+
+func (c *Config) WebhooksMaxRetryInterval() time.Duration {
+	return c.config.Webhooks.MaxRetryInterval
+}
+
+// WebhooksConcurrency is number of deliveries sent at once by every replica
+func (c *Config) WebhooksConcurrency() int {
+	return c.config.Webhooks.Concurrency
+}
+
+func (c *Config) WebhooksRetention() time.Duration {
+	return c.config.Webhooks.Retention
+}
+
+func (c *Config) WebhooksLogSize() int {
+	return c.config.Webhooks.LogSize
+}
+
+// AccessLogSampleEvery is how often successful requests are logged, 1 means every request
+func (c *Config) AccessLogSampleEvery() int {
+	return c.config.AccessLog.SampleEvery
+}
+
+// AccessLogSkipPaths are request paths which are never logged
+func (c *Config) AccessLogSkipPaths() []string {
+	return c.config.AccessLog.SkipPaths
+}
+
+func (c *Config) Tracing() TracingConfig {
+	return c.config.Tracing
 }
 
 func (c *Config) LogLevel() zerolog.Level {
@@ -43,17 +363,130 @@ func (c *Config) LogLevel() zerolog.Level {
 	return level
 }
 
-func LoadConfig() (*Config, error) {
-	cfg := config{
+func defaultConfig() config {
+	return config{
 		LogLevel: zerolog.LevelInfoValue,
+		HTTP: httpConfig{
+			AdminServerPort:  "9090",
+			ReadinessTimeout: 2 * time.Second,
+		},
+		Mongo: MongoConfig{
+			Database:        "news",
+			PostsCollection: "posts",
+			TrashCollection: "posts_trash",
+			UsersCollection: "users",
+			MigrateOnStart:  true,
+		},
+		Posts: postsConfig{
+			PageSize:         4,
+			TitleMaxLength:   100,
+			ContentMaxLength: 50000,
+			SearchMinLength:  3,
+			Count:            "estimated",
+			CountRefresh:     time.Minute,
+			SearchCountLimit: 1000,
+		},
+		Security: SecurityConfig{
+			FrameAncestors:    []string{"'none'"},
+			ReferrerPolicy:    "strict-origin-when-cross-origin",
+			PermissionsPolicy: "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		},
+		RateLimit: rateLimitConfig{
+			Store:     "memory",
+			Search:    "30/1m",
+			Create:    "10/1h",
+			Login:     "5/1m",
+			CSPReport: "60/1m",
+		},
+		Cache: cacheConfig{
+			Size:         1000,
+			TTL:          30 * time.Second,
+			Invalidation: "none",
+		},
+		Live: liveConfig{
+			Buffer:       16,
+			Heartbeat:    15 * time.Second,
+			PollInterval: 2 * time.Second,
+		},
+		Webhooks: webhooksConfig{
+			Timeout:          10 * time.Second,
+			MaxAttempts:      10,
+			RetryInterval:    10 * time.Second,
+			MaxRetryInterval: time.Hour,
+			Concurrency:      4,
+			Retention:        7 * 24 * time.Hour,
+			LogSize:          50,
+		},
+		AccessLog: accessLogConfig{
+			SampleEvery: 1,
+			SkipPaths:   []string{"/health", "/livez", "/readyz"},
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		Shutdown: shutdownConfig{
+			Timeout:    5 * time.Second,
+			DrainDelay: 5 * time.Second,
+		},
 	}
+}
+
+// LoadConfig merges defaults, optional config file from APP_CONFIG_FILE and env variables, in order of precedence
+func LoadConfig() (*Config, error) {
+	cfg := defaultConfig()
+	sources := make(map[string]Source)
 
+	if path := os.Getenv(FileEnv); path != "" {
+		keys, err := readFile(path, &cfg)
+		if err != nil {
+			return nil, fmt.Errorf("config: unable to read config file: %w", err)
+		}
+		for _, key := range keys {
+			sources[key] = SourceFile
+		}
+	}
 	if err := cleanenv.ReadEnv(&cfg); err != nil {
 		return nil, fmt.Errorf("config: unable to read env variables: %w", err)
 	}
-	if err := validator.New(validator.WithRequiredStructEnabled()).Struct(cfg); err != nil {
+	if err := readFileVars(&cfg); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	for _, f := range fields(&cfg) {
+		if _, ok := os.LookupEnv(f.env); ok {
+			sources[f.key] = SourceEnv
+		} else if _, ok := os.LookupEnv(f.env + "_FILE"); ok {
+			sources[f.key] = SourceEnvFile
+		}
+	}
+
+	if err := newValidator().Struct(cfg); err != nil {
 		return nil, fmt.Errorf("config: config has invalid values: %w", err)
 	}
 
-	return &Config{config: cfg}, nil
+	return &Config{config: cfg, sources: sources}, nil
+}
+
+func newValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	_ = validate.RegisterValidation("mongodb_uri", func(fl validator.FieldLevel) bool {
+		_, err := connstring.ParseAndValidate(fl.Field().String())
+		return err == nil
+	})
+	// "majority" or a number of acknowledging nodes
+	_ = validate.RegisterValidation("write_concern", func(fl validator.FieldLevel) bool {
+		w := fl.Field().String()
+		n, err := strconv.Atoi(w)
+		return w == "majority" || (err == nil && n >= 0)
+	})
+	// not above MaxPoolSize unless the latter is left to URI or driver
+	_ = validate.RegisterValidation("min_pool_size", func(fl validator.FieldLevel) bool {
+		maxSize := fl.Parent().FieldByName("MaxPoolSize").Uint()
+		return maxSize == 0 || fl.Field().Uint() <= maxSize
+	})
+	_ = validate.RegisterValidation("rate_limit", func(fl validator.FieldLevel) bool {
+		_, err := parseRateLimit(fl.Field().String())
+		return err == nil
+	})
+	return validate
 }
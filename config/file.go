@@ -0,0 +1,129 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Source is where effective config value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceEnvFile Source = "env file"
+)
+
+// Setting is a single effective config value, secrets are redacted
+type Setting struct {
+	Key    string
+	Env    string
+	Value  string
+	Source Source
+}
+
+// Settings lists all effective config values in declaration order
+func (c *Config) Settings() []Setting {
+	cfg := c.config
+	var settings []Setting
+	for _, f := range fields(&cfg) {
+		source, ok := c.sources[f.key]
+		if !ok {
+			source = SourceDefault
+		}
+		settings = append(settings, Setting{
+			Key:    f.key,
+			Env:    f.env,
+			Value:  fmt.Sprint(f.value.Interface()),
+			Source: source,
+		})
+	}
+	return settings
+}
+
+// field is a config leaf value addressed by dotted file key, e.g. "mongo.uri"
+type field struct {
+	key   string
+	env   string
+	value reflect.Value
+}
+
+func fields(cfg *config) []field {
+	return collectFields(reflect.ValueOf(cfg).Elem(), "")
+}
+
+func collectFields(v reflect.Value, prefix string) []field {
+	var result []field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		key := prefix + sf.Tag.Get("yaml")
+		if sf.Type.Kind() == reflect.Struct {
+			result = append(result, collectFields(v.Field(i), key+".")...)
+			continue
+		}
+		result = append(result, field{key: key, env: sf.Tag.Get("env"), value: v.Field(i)})
+	}
+	return result
+}
+
+// readFile decodes YAML or TOML file into cfg, format is chosen by file extension.
+// Unknown keys are rejected, so typos do not silently fall back to defaults.
+// It returns dotted keys present in the file
+func readFile(path string, cfg *config) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(content))
+		dec.KnownFields(true)
+		// empty file is a valid config
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		md, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return nil, err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("unknown key %q", undecoded[0].String())
+		}
+		if _, err := toml.Decode(string(content), &raw); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", ext)
+	}
+
+	return flattenKeys(raw, ""), nil
+}
+
+func flattenKeys(m map[string]any, prefix string) []string {
+	var keys []string
+	for k, v := range m {
+		if nested, ok := v.(map[string]any); ok {
+			keys = append(keys, flattenKeys(nested, prefix+k+".")...)
+			continue
+		}
+		keys = append(keys, prefix+k)
+	}
+	return keys
+}
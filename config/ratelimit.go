@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is a token bucket holding up to Requests tokens, refilled with Requests tokens per Period.
+// Zero limit disables limiting
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// parseRateLimit parses "REQUESTS/PERIOD", e.g. "30/1m", period unit may be omitted, e.g. "10/h",
+// empty string is zero limit
+func parseRateLimit(s string) (RateLimit, error) {
+	if s == "" {
+		return RateLimit{}, nil
+	}
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid limit %q: want REQUESTS/PERIOD", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return RateLimit{}, fmt.Errorf("invalid limit %q: requests must be positive number", s)
+	}
+	if period != "" && strings.Trim(period, "smh") == "" {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid limit %q: period must be positive duration", s)
+	}
+	return RateLimit{Requests: n, Period: d}, nil
+}
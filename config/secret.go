@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret is a string which never reveals itself in logs, fmt or JSON output, use Value to get the real value
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// readFileVars sets string fields from files referenced by <ENV>_FILE variables,
+// e.g. APP_MONGO_URI_FILE=/run/secrets/mongo_uri, as used by docker and kubernetes secrets
+func readFileVars(cfg *config) error {
+	for _, f := range fields(cfg) {
+		if f.env == "" || f.value.Kind() != reflect.String {
+			continue
+		}
+		path, ok := os.LookupEnv(f.env + "_FILE")
+		if !ok || path == "" {
+			continue
+		}
+		if _, ok := os.LookupEnv(f.env); ok {
+			return fmt.Errorf("both %s and %s_FILE are set", f.env, f.env)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read %s_FILE: %w", f.env, err)
+		}
+		f.value.SetString(strings.TrimRight(string(content), "\r\n"))
+	}
+	return nil
+}
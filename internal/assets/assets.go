@@ -0,0 +1,119 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// dist holds htmx with its sse extension and stylesheet built from tailwind.css by `make css`
+//
+//go:embed dist
+var dist embed.FS
+
+// Default is manifest of embedded assets
+var Default = mustManifest(dist, "dist")
+
+// CacheControl is sent with every asset, file name changes with content, so it never has to be revalidated
+const CacheControl = "public, max-age=31536000, immutable"
+
+// Asset is a file served under content-hashed name
+type Asset struct {
+	// Name is original file name, e.g. htmx.min.js
+	Name string
+	// File is content-hashed file name, e.g. htmx.min.0123456789.js
+	File string
+	// Integrity is subresource integrity hash
+	Integrity string
+	Content   []byte
+}
+
+// Manifest maps original asset names to content-hashed ones
+type Manifest struct {
+	byName map[string]*Asset
+	byFile map[string]*Asset
+}
+
+// NewManifest reads all files of dir in fsys
+func NewManifest(fsys fs.FS, dir string) (*Manifest, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{byName: make(map[string]*Asset), byFile: make(map[string]*Asset)}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		asset := newAsset(entry.Name(), content)
+		m.byName[asset.Name] = asset
+		m.byFile[asset.File] = asset
+	}
+	return m, nil
+}
+
+func mustManifest(fsys fs.FS, dir string) *Manifest {
+	m, err := NewManifest(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func newAsset(name string, content []byte) *Asset {
+	hash := sha256.Sum256(content)
+	ext := path.Ext(name)
+	integrity := sha512.Sum384(content)
+	return &Asset{
+		Name:      name,
+		File:      fmt.Sprintf("%s.%s%s", strings.TrimSuffix(name, ext), hex.EncodeToString(hash[:5]), ext),
+		Integrity: "sha384-" + base64.StdEncoding.EncodeToString(integrity[:]),
+		Content:   content,
+	}
+}
+
+// Get panics if there is no asset with such name, as it's a programming error
+func (m *Manifest) Get(name string) *Asset {
+	asset, ok := m.byName[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown asset %q", name))
+	}
+	return asset
+}
+
+// All lists assets sorted by name
+func (m *Manifest) All() []*Asset {
+	all := make([]*Asset, 0, len(m.byName))
+	for _, asset := range m.byName {
+		all = append(all, asset)
+	}
+	slices.SortFunc(all, func(a, b *Asset) int { return strings.Compare(a.Name, b.Name) })
+	return all
+}
+
+// Handler serves assets by content-hashed name from "file" path param, stale names are not found
+func (m *Manifest) Handler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		asset, ok := m.byFile[c.Param("file")]
+		if !ok {
+			return echo.ErrNotFound
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, CacheControl)
+		return c.Blob(http.StatusOK, mime.TypeByExtension(path.Ext(asset.Name)), asset.Content)
+	}
+}
@@ -0,0 +1,62 @@
+package assets_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/assets"
+)
+
+func TestNewManifest(t *testing.T) {
+	m, err := assets.NewManifest(fstest.MapFS{
+		"dist/app.css": {Data: []byte("body{}")},
+	}, "dist")
+	require.NoError(t, err)
+
+	asset := m.Get("app.css")
+	assert.Regexp(t, `^app\.[0-9a-f]{10}\.css$`, asset.File)
+	// same as `openssl dgst -sha384 -binary | base64`
+	assert.Equal(t, "sha384-myyg/hQ74aSgjBBvVME/QXAXEkT4Y9dHbVQ5C0lIyGpldvNLJV2IWc5ElXbqLi06", asset.Integrity)
+	assert.Panics(t, func() { m.Get("missing.js") })
+
+	// hashed name changes with content
+	changed, err := assets.NewManifest(fstest.MapFS{
+		"dist/app.css": {Data: []byte("body{color:red}")},
+	}, "dist")
+	require.NoError(t, err)
+	assert.NotEqual(t, asset.File, changed.Get("app.css").File)
+}
+
+func TestDefault(t *testing.T) {
+	names := make([]string, 0)
+	for _, asset := range assets.Default.All() {
+		names = append(names, asset.Name)
+	}
+	assert.Equal(t, []string{"app.css", "htmx-ext-sse.js", "htmx.min.js"}, names)
+}
+
+func TestManifest_Handler(t *testing.T) {
+	e := echo.New()
+	e.GET("/assets/:file", assets.Default.Handler())
+	htmx := assets.Default.Get("htmx.min.js")
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/"+htmx.File, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, assets.CacheControl, rec.Header().Get(echo.HeaderCacheControl))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), "javascript")
+	assert.Equal(t, htmx.Content, rec.Body.Bytes())
+
+	// original and stale names are not served
+	for _, file := range []string{"htmx.min.js", "htmx.min.0000000000.js"} {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/"+file, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	MigrationsCollection     = "schema_migrations"
+	MigrationsLockCollection = "schema_migrations_lock"
+	migrationsLockID         = "migrations"
+
+	// DefaultLockTTL is how long the lock is held if the owner dies without releasing it
+	DefaultLockTTL = time.Minute
+)
+
+var (
+	ErrLocked   = errors.New("migrations are locked by another process")
+	ErrLockLost = errors.New("migrations lock is lost")
+)
+
+// Migration changes database schema, Down must revert Up
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error
+	Down        func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error
+}
+
+// MigrationStatus is a migration and time it was applied at, AppliedAt is nil for pending migrations
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time
+}
+
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+type Migrator struct {
+	mongoClient *mongo.Client
+	cfg         ClientConfig
+	migrations  []Migration
+	owner       string
+	// LockWait is how long to wait for the lock held by another process
+	LockWait time.Duration
+	// LockTTL is renewed every third of it while migrations run, they are cancelled when renewal fails until it expires
+	LockTTL time.Duration
+}
+
+// NewMigrator creates migrator for migrations sorted by version, versions must be unique
+func NewMigrator(mongoClient *mongo.Client, cfg ClientConfig, migrations []Migration) (*Migrator, error) {
+	sorted := slices.SortedFunc(slices.Values(migrations), func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", sorted[i].Version)
+		}
+	}
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		mongoClient: mongoClient,
+		cfg:         cfg,
+		migrations:  sorted,
+		owner:       fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		LockWait:    time.Minute,
+		LockTTL:     DefaultLockTTL,
+	}, nil
+}
+
+func (m *Migrator) collection() *mongo.Collection {
+	return m.mongoClient.Database(m.cfg.database()).Collection(MigrationsCollection)
+}
+
+// Up applies all pending migrations in order
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			zerolog.Ctx(ctx).Info().Int("version", migration.Version).Str("description", migration.Description).Msg("applying migration")
+			if err := migration.Up(ctx, m.mongoClient, m.cfg); err != nil {
+				return fmt.Errorf("migration %04d: %w", migration.Version, err)
+			}
+			if _, err := m.collection().InsertOne(ctx, appliedMigration{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   time.Now(),
+			}); err != nil {
+				return fmt.Errorf("migration %04d: unable to record: %w", migration.Version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range slices.Backward(m.migrations) {
+			if steps <= 0 {
+				break
+			}
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			zerolog.Ctx(ctx).Info().Int("version", migration.Version).Str("description", migration.Description).Msg("reverting migration")
+			if err := migration.Down(ctx, m.mongoClient, m.cfg); err != nil {
+				return fmt.Errorf("migration %04d: %w", migration.Version, err)
+			}
+			if _, err := m.collection().DeleteOne(ctx, bson.D{{Key: "_id", Value: migration.Version}}); err != nil {
+				return fmt.Errorf("migration %04d: unable to record: %w", migration.Version, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status lists all known migrations
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if a, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &a.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	cursor, err := m.collection().Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var all []appliedMigration
+	if err := cursor.All(ctx, &all); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]appliedMigration, len(all))
+	for _, a := range all {
+		applied[a.Version] = a
+	}
+	return applied, nil
+}
+
+// withLock runs fn while holding distributed lock, so only one replica migrates at a time.
+// Lock is a single document, it can be taken only when it's missing or expired.
+// ctx of fn is cancelled when the lock is lost
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	locks := m.mongoClient.Database(m.cfg.database()).Collection(MigrationsLockCollection)
+
+	var expiresAt time.Time
+	acquire := func() error {
+		now := time.Now()
+		_, err := locks.UpdateOne(ctx,
+			bson.D{
+				{Key: "_id", Value: migrationsLockID},
+				{Key: "expiresAt", Value: bson.D{{Key: "$lt", Value: now}}},
+			},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "owner", Value: m.owner},
+				{Key: "expiresAt", Value: now.Add(m.LockTTL)},
+			}}},
+			options.UpdateOne().SetUpsert(true),
+		)
+		// lock is held by another process, so upsert conflicts with the existing document
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrLocked
+		}
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		expiresAt = now.Add(m.LockTTL)
+		return nil
+	}
+
+	bo := backoff.NewExponentialBackOff()
+	bo.MaxInterval = 5 * time.Second
+	bo.MaxElapsedTime = m.LockWait
+	if err := backoff.Retry(acquire, backoff.WithContext(bo, ctx)); err != nil {
+		return fmt.Errorf("unable to acquire migrations lock: %w", err)
+	}
+	defer func() {
+		// release even if ctx is cancelled
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_, _ = locks.DeleteOne(ctx, bson.D{{Key: "_id", Value: migrationsLockID}, {Key: "owner", Value: m.owner}})
+	}()
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		m.renewLock(lockCtx, locks, expiresAt, cancel)
+	}()
+	err := fn(lockCtx)
+	cancel(nil)
+	<-renewed
+	if cause := context.Cause(lockCtx); errors.Is(cause, ErrLockLost) {
+		return cause
+	}
+	return err
+}
+
+// renewLock extends the lock until ctx is done, lost is called when the lock is taken by another process
+// or it can't be renewed before expiration
+func (m *Migrator) renewLock(ctx context.Context, locks *mongo.Collection, expiresAt time.Time, lost context.CancelCauseFunc) {
+	ticker := time.NewTicker(m.LockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		res, err := locks.UpdateOne(ctx,
+			bson.D{{Key: "_id", Value: migrationsLockID}, {Key: "owner", Value: m.owner}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "expiresAt", Value: now.Add(m.LockTTL)}}}},
+		)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err == nil && res.MatchedCount == 0:
+			lost(ErrLockLost)
+			return
+		case err == nil:
+			expiresAt = now.Add(m.LockTTL)
+		case time.Now().Add(m.LockTTL / 3).After(expiresAt):
+			// the next attempt would be too late
+			lost(fmt.Errorf("%w: %w", ErrLockLost, err))
+			return
+		default:
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("unable to renew migrations lock")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/dbtest"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.Mongo(t)
+	cfg := db.ClientConfig{}
+
+	migrator, err := db.NewMigrator(mongoClient, cfg, db.Migrations)
+	require.NoError(t, err)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(db.Migrations))
+	assert.Nil(t, statuses[0].AppliedAt)
+
+	// up is idempotent
+	require.NoError(t, migrator.Up(ctx))
+	require.NoError(t, migrator.Up(ctx))
+	require.NoError(t, db.TextIndexCheck(db.GetPostsCollection(mongoClient, cfg))(ctx))
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.NotNil(t, s.AppliedAt, s.Version)
+	}
+
+	// revert everything
+	require.NoError(t, migrator.Down(ctx, len(db.Migrations)))
+	assert.Error(t, db.TextIndexCheck(db.GetPostsCollection(mongoClient, cfg))(ctx))
+	statuses, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, statuses[0].AppliedAt)
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.Mongo(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow, err := db.NewMigrator(mongoClient, db.ClientConfig{}, []db.Migration{{
+		Version: 1,
+		Up: func(context.Context, *mongo.Client, db.ClientConfig) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}})
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() { done <- slow.Up(ctx) }()
+	<-started
+
+	// lock is held by the slow migrator
+	other, err := db.NewMigrator(mongoClient, db.ClientConfig{}, nil)
+	require.NoError(t, err)
+	other.LockWait = 500 * time.Millisecond
+	err = other.Up(ctx)
+	assert.True(t, errors.Is(err, db.ErrLocked), err)
+
+	close(release)
+	require.NoError(t, <-done)
+	// lock is released
+	require.NoError(t, other.Up(ctx))
+}
+
+func TestMigrator_LockRenewal(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.Mongo(t)
+	locks := mongoClient.Database(db.DefaultDatabase).Collection(db.MigrationsLockCollection)
+
+	// migration outlives lock ttl, lock is renewed meanwhile
+	other, err := db.NewMigrator(mongoClient, db.ClientConfig{}, nil)
+	require.NoError(t, err)
+	other.LockWait = 100 * time.Millisecond
+	slow, err := db.NewMigrator(mongoClient, db.ClientConfig{}, []db.Migration{{
+		Version: 1,
+		Up: func(context.Context, *mongo.Client, db.ClientConfig) error {
+			time.Sleep(time.Second)
+			assert.ErrorIs(t, other.Up(ctx), db.ErrLocked)
+			return nil
+		},
+	}})
+	require.NoError(t, err)
+	slow.LockTTL = 300 * time.Millisecond
+	require.NoError(t, slow.Up(ctx))
+
+	// lock is taken by another process, migration is cancelled
+	lost, err := db.NewMigrator(mongoClient, db.ClientConfig{}, []db.Migration{{
+		Version: 2,
+		Up: func(ctx context.Context, _ *mongo.Client, _ db.ClientConfig) error {
+			_, err := locks.UpdateOne(ctx, bson.M{"_id": "migrations"}, bson.M{"$set": bson.M{"owner": "another"}})
+			require.NoError(t, err)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}})
+	require.NoError(t, err)
+	lost.LockTTL = 300 * time.Millisecond
+	assert.ErrorIs(t, lost.Up(ctx), db.ErrLockLost)
+}
+
+func TestNewMigrator_DuplicateVersion(t *testing.T) {
+	_, err := db.NewMigrator(nil, db.ClientConfig{}, []db.Migration{{Version: 1}, {Version: 1}})
+	assert.Error(t, err)
+}
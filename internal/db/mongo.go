@@ -1,24 +1,181 @@
 package db
 
 import (
+	"cmp"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/rs/zerolog/log"
-	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
 )
 
 const (
-	Name            = "news"
-	PostsCollection = "posts"
+	DefaultDatabase        = "news"
+	DefaultPostsCollection = "posts"
+	DefaultTrashCollection = "posts_trash"
+	DefaultUsersCollection = "users"
+	DefaultAppName         = "news"
+
+	// RateLimitsCollection holds rate limiter buckets shared between replicas
+	RateLimitsCollection = "rate_limits"
+	// CountersCollection holds counters refreshed periodically instead of counting documents on every request
+	CountersCollection = "counters"
+	// CacheInvalidationsCollection is capped collection tailed by replicas to invalidate their posts cache
+	CacheInvalidationsCollection = "cache_invalidations"
+	// CacheInvalidationsSize is max size of CacheInvalidationsCollection in bytes
+	CacheInvalidationsSize = 1 << 20
+	// WebhooksCollection holds webhook subscriptions
+	WebhooksCollection = "webhooks"
+	// WebhookDeliveriesCollection is the queue and the log of webhook deliveries
+	WebhookDeliveriesCollection = "webhook_deliveries"
+
+	// TrashTTL is how long deleted posts can be restored
+	TrashTTL = time.Hour
 )
 
-func CreateMongoClient(ctx context.Context, uri string) (*mongo.Client, error) {
+// ClientConfig holds client options which are not part of URI or override it, zero values keep driver defaults
+type ClientConfig struct {
+	URI string
+
+	MaxPoolSize     uint64
+	MinPoolSize     uint64
+	MaxConnIdleTime time.Duration
+
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	// OperationTimeout limits every operation including retries, driver v2 uses it instead of socket timeout
+	OperationTimeout time.Duration
+
+	// ReadConcern is a read concern level, e.g. "majority"
+	ReadConcern string
+	// WriteConcern is "majority" or a number of acknowledging nodes
+	WriteConcern string
+
+	// TLSCAFile is PEM file with root certificates used to verify server
+	TLSCAFile string
+	// TLSCertFile and TLSKeyFile are PEM files with client certificate for x.509 authentication
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Compressors are "snappy", "zlib" or "zstd" in order of preference
+	Compressors []string
+	AppName     string
+
+	Database        string
+	PostsCollection string
+	TrashCollection string
+	UsersCollection string
+}
+
+func (cfg ClientConfig) database() string {
+	return cmp.Or(cfg.Database, DefaultDatabase)
+}
+
+// ClientOptions converts cfg to driver options
+func ClientOptions(cfg ClientConfig) (*options.ClientOptions, error) {
+	// default app name is set before URI, so appName of URI overrides it
+	opts := options.Client().SetAppName(DefaultAppName).ApplyURI(cfg.URI)
+	if cfg.AppName != "" {
+		opts.SetAppName(cfg.AppName)
+	}
+	if cfg.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(cfg.MaxPoolSize)
+	}
+	if cfg.MinPoolSize > 0 {
+		opts.SetMinPoolSize(cfg.MinPoolSize)
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+	}
+	if cfg.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(cfg.ConnectTimeout)
+	}
+	if cfg.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+	if cfg.OperationTimeout > 0 {
+		opts.SetTimeout(cfg.OperationTimeout)
+	}
+	if cfg.ReadConcern != "" {
+		opts.SetReadConcern(&readconcern.ReadConcern{Level: cfg.ReadConcern})
+	}
+	if cfg.WriteConcern != "" {
+		wc, err := parseWriteConcern(cfg.WriteConcern)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetWriteConcern(wc)
+	}
+	if len(cfg.Compressors) > 0 {
+		opts.SetCompressors(cfg.Compressors)
+	}
+	if cfg.TLSCAFile != "" || cfg.TLSCertFile != "" {
+		tlsCfg, err := tlsConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsCfg)
+	}
+	return opts, nil
+}
+
+func parseWriteConcern(w string) (*writeconcern.WriteConcern, error) {
+	if w == writeconcern.WCMajority {
+		return writeconcern.Majority(), nil
+	}
+	n, err := strconv.Atoi(w)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid write concern %q", w)
+	}
+	return &writeconcern.WriteConcern{W: n}, nil
+}
+
+func tlsConfig(cfg ClientConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tls ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("tls ca file has no certificates")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cmp.Or(cfg.TLSKeyFile, cfg.TLSCertFile))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tls client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// CreateMongoClient connects to mongodb, optional monitors are notified about every command.
+// Indexes are created by migrations, see Migrations
+func CreateMongoClient(ctx context.Context, cfg ClientConfig, monitors ...*event.CommandMonitor) (*mongo.Client, error) {
 	logger := log.Ctx(ctx)
-	mongoClient, err := mongo.Connect(options.Client().ApplyURI(uri))
+	opts, err := ClientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(monitors) > 0 {
+		opts.SetMonitor(combineMonitors(monitors))
+	}
+	mongoClient, err := mongo.Connect(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -36,24 +193,88 @@ func CreateMongoClient(ctx context.Context, uri string) (*mongo.Client, error) {
 		return nil, err
 	}
 
-	// create weighted full-text index
-	coll := mongoClient.Database(Name).Collection(PostsCollection)
-	if _, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys: bson.D{
-			{Key: "title", Value: "text"},
-			{Key: "content", Value: "text"},
+	return mongoClient, nil
+}
+
+func GetPostsCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(cmp.Or(cfg.PostsCollection, DefaultPostsCollection))
+}
+
+func GetTrashCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(cmp.Or(cfg.TrashCollection, DefaultTrashCollection))
+}
+
+func GetUsersCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(cmp.Or(cfg.UsersCollection, DefaultUsersCollection))
+}
+
+func GetRateLimitsCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(RateLimitsCollection)
+}
+
+func GetCountersCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(CountersCollection)
+}
+
+func GetWebhooksCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(WebhooksCollection)
+}
+
+func GetWebhookDeliveriesCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(WebhookDeliveriesCollection)
+}
+
+func GetCacheInvalidationsCollection(mongoClient *mongo.Client, cfg ClientConfig) *mongo.Collection {
+	return mongoClient.Database(cfg.database()).Collection(CacheInvalidationsCollection)
+}
+
+// combineMonitors fans out command events to all monitors, as client accepts only one
+func combineMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
 		},
-		Options: options.Index().SetWeights(bson.D{
-			{Key: "title", Value: 10},
-			{Key: "content", Value: 1},
-		}),
-	}); err != nil {
-		return nil, err
 	}
+}
 
-	return mongoClient, nil
+// PingCheck reports whether mongodb is reachable
+func PingCheck(mongoClient *mongo.Client) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return mongoClient.Ping(ctx, nil)
+	}
 }
 
-func GetPostsCollection(mongoClient *mongo.Client) *mongo.Collection {
-	return mongoClient.Database(Name).Collection(PostsCollection)
+// TextIndexCheck reports whether full-text index used by search exists
+func TextIndexCheck(coll *mongo.Collection) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		specs, err := coll.Indexes().ListSpecifications(ctx)
+		if err != nil {
+			return err
+		}
+		for _, spec := range specs {
+			// text indexes are stored with special "_fts" key
+			if _, err := spec.KeysDocument.LookupErr("_fts"); err == nil {
+				return nil
+			}
+		}
+		return errors.New("text index does not exist")
+	}
 }
@@ -0,0 +1,80 @@
+package db_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo/writeconcern"
+
+	"github.com/mineroot/news/internal/db"
+)
+
+func TestClientOptions(t *testing.T) {
+	opts, err := db.ClientOptions(db.ClientConfig{
+		URI:              "mongodb://localhost:27017",
+		MaxPoolSize:      20,
+		ConnectTimeout:   5 * time.Second,
+		OperationTimeout: time.Second,
+		ReadConcern:      "majority",
+		WriteConcern:     "2",
+		Compressors:      []string{"zstd", "snappy"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, db.DefaultAppName, *opts.AppName)
+	assert.Equal(t, uint64(20), *opts.MaxPoolSize)
+	assert.Equal(t, 5*time.Second, *opts.ConnectTimeout)
+	assert.Equal(t, time.Second, *opts.Timeout)
+	assert.Equal(t, "majority", opts.ReadConcern.Level)
+	assert.Equal(t, 2, opts.WriteConcern.W)
+	assert.Equal(t, []string{"zstd", "snappy"}, opts.Compressors)
+	assert.Nil(t, opts.TLSConfig)
+
+	opts, err = db.ClientOptions(db.ClientConfig{URI: "mongodb://localhost:27017", WriteConcern: "majority"})
+	require.NoError(t, err)
+	assert.Equal(t, writeconcern.WCMajority, opts.WriteConcern.W)
+
+	_, err = db.ClientOptions(db.ClientConfig{URI: "mongodb://localhost:27017", WriteConcern: "all"})
+	assert.Error(t, err)
+}
+
+func TestClientOptions_URI(t *testing.T) {
+	uri := "mongodb://localhost:27017/?appName=reports&maxPoolSize=5&connectTimeoutMS=1000&serverSelectionTimeoutMS=2000"
+
+	// zero values keep URI options
+	opts, err := db.ClientOptions(db.ClientConfig{URI: uri})
+	require.NoError(t, err)
+	assert.Equal(t, "reports", *opts.AppName)
+	assert.Equal(t, uint64(5), *opts.MaxPoolSize)
+	assert.Equal(t, time.Second, *opts.ConnectTimeout)
+	assert.Equal(t, 2*time.Second, *opts.ServerSelectionTimeout)
+
+	// configured values override them
+	opts, err = db.ClientOptions(db.ClientConfig{URI: uri, AppName: "news-worker", MaxPoolSize: 50, ConnectTimeout: 3 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, "news-worker", *opts.AppName)
+	assert.Equal(t, uint64(50), *opts.MaxPoolSize)
+	assert.Equal(t, 3*time.Second, *opts.ConnectTimeout)
+	assert.Equal(t, 2*time.Second, *opts.ServerSelectionTimeout)
+}
+
+func TestClientOptions_TLS(t *testing.T) {
+	dir := t.TempDir()
+
+	// missing file
+	_, err := db.ClientOptions(db.ClientConfig{URI: "mongodb://localhost:27017", TLSCAFile: filepath.Join(dir, "ca.pem")})
+	assert.Error(t, err)
+
+	// file without certificates
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+	_, err = db.ClientOptions(db.ClientConfig{URI: "mongodb://localhost:27017", TLSCAFile: caFile})
+	assert.ErrorContains(t, err, "no certificates")
+
+	// invalid client certificate
+	_, err = db.ClientOptions(db.ClientConfig{URI: "mongodb://localhost:27017", TLSCertFile: caFile})
+	assert.ErrorContains(t, err, "client certificate")
+}
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// index names are the ones generated by mongodb, so databases created before migrations are compatible
+const (
+	textIndexName      = "title_text_content_text"
+	trashTTLIndexName  = "deletedAt_1"
+	userEmailIndexName = "email_1"
+	expiresAtIndexName = "expiresAt_1"
+	updatedAtIndexName = "updatedAt_1"
+	dueIndexName       = "status_1_nextAttemptAt_1"
+	createdAtIndexName = "createdAt_-1"
+)
+
+// textIndexWeights make title matches more relevant than content ones
+var textIndexWeights = map[string]float64{"title": 10, "content": 1}
+
+// textIndexModel is full-text index used by search
+func textIndexModel() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "title", Value: "text"},
+			{Key: "content", Value: "text"},
+		},
+		Options: options.Index().SetName(textIndexName).SetWeights(bson.D{
+			{Key: "title", Value: textIndexWeights["title"]},
+			{Key: "content", Value: textIndexWeights["content"]},
+		}),
+	}
+}
+
+// RebuildTextIndex creates full-text index again after its definition is changed, it reports whether index is rebuilt.
+// Index matching the definition is kept unless force is set. MongoDB allows a single text index per collection,
+// so the new one can't be built next to the old one, search is unavailable until it's built
+func RebuildTextIndex(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig, force bool) (bool, error) {
+	indexes := GetPostsCollection(mongoClient, cfg).Indexes()
+	current, err := findTextIndex(ctx, indexes)
+	if err != nil {
+		return false, err
+	}
+	if current != nil {
+		if !force && current.Name == textIndexName && maps.Equal(current.Weights, textIndexWeights) {
+			return false, nil
+		}
+		// index may be created before migrations under another name
+		if err := indexes.DropOne(ctx, current.Name); err != nil && !isIndexNotFound(err) {
+			return false, err
+		}
+	}
+	if _, err := indexes.CreateOne(ctx, textIndexModel()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// textIndex is definition of existing full-text index
+type textIndex struct {
+	Name    string             `bson:"name"`
+	Weights map[string]float64 `bson:"weights"`
+}
+
+// findTextIndex returns nil when collection has no full-text index
+func findTextIndex(ctx context.Context, indexes mongo.IndexView) (*textIndex, error) {
+	cursor, err := indexes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		// text indexes are stored with special "_fts" key
+		keys, ok := cursor.Current.Lookup("key").DocumentOK()
+		if !ok {
+			continue
+		}
+		if _, err := keys.LookupErr("_fts"); err != nil {
+			continue
+		}
+		var index textIndex
+		if err := cursor.Decode(&index); err != nil {
+			return nil, err
+		}
+		return &index, nil
+	}
+	return nil, cursor.Err()
+}
+
+// isIndexNotFound reports whether err is mongodb IndexNotFound error
+// ensureCapped creates capped collection. Mongodb creates an uncapped one on the first insert, e.g. when a replica
+// runs without migrations, then it's converted, as tailable cursors fail on uncapped collections
+func ensureCapped(ctx context.Context, database *mongo.Database, name string, size int64) error {
+	specs, err := database.ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: name}})
+	if err != nil {
+		return err
+	}
+	if len(specs) == 0 {
+		return database.CreateCollection(ctx, name, options.CreateCollection().SetCapped(true).SetSizeInBytes(size))
+	}
+	if capped, ok := specs[0].Options.Lookup("capped").BooleanOK(); ok && capped {
+		return nil
+	}
+	err = database.RunCommand(ctx, bson.D{{Key: "convertToCapped", Value: name}, {Key: "size", Value: size}}).Err()
+	if err != nil {
+		return fmt.Errorf("unable to convert %s to capped collection: %w", name, err)
+	}
+	return nil
+}
+
+func isIndexNotFound(err error) bool {
+	var ce mongo.CommandError
+	return errors.As(err, &ce) && ce.Code == 27
+}
+
+// Migrations are all app schema migrations, new migrations are appended with next version
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create weighted full-text index on posts",
+		Up: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			_, err := GetPostsCollection(mongoClient, cfg).Indexes().CreateOne(ctx, textIndexModel())
+			return err
+		},
+		Down: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			return GetPostsCollection(mongoClient, cfg).Indexes().DropOne(ctx, textIndexName)
+		},
+	},
+	{
+		Version:     2,
+		Description: "purge deleted posts from trash after TrashTTL",
+		Up: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			_, err := GetTrashCollection(mongoClient, cfg).Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+				Options: options.Index().SetName(trashTTLIndexName).SetExpireAfterSeconds(int32(TrashTTL.Seconds())),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			return GetTrashCollection(mongoClient, cfg).Indexes().DropOne(ctx, trashTTLIndexName)
+		},
+	},
+	{
+		Version:     3,
+		Description: "create unique index on users email",
+		Up: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			_, err := GetUsersCollection(mongoClient, cfg).Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "email", Value: 1}},
+				Options: options.Index().SetName(userEmailIndexName).SetUnique(true),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			return GetUsersCollection(mongoClient, cfg).Indexes().DropOne(ctx, userEmailIndexName)
+		},
+	},
+	{
+		Version:     4,
+		Description: "expire rate limiter buckets",
+		Up: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			_, err := GetRateLimitsCollection(mongoClient, cfg).Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+				Options: options.Index().SetName(expiresAtIndexName).SetExpireAfterSeconds(0),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			return GetRateLimitsCollection(mongoClient, cfg).Indexes().DropOne(ctx, expiresAtIndexName)
+		},
+	},
+	{
+		Version:     5,
+		Description: "create capped collection for posts cache invalidations",
+		Up: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			return ensureCapped(ctx, mongoClient.Database(cfg.database()), CacheInvalidationsCollection, CacheInvalidationsSize)
+		},
+		Down: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			return GetCacheInvalidationsCollection(mongoClient, cfg).Drop(ctx)
+		},
+	},
+	{
+		Version:     6,
+		Description: "index posts by update time for live updates polling",
+		Up: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			_, err := GetPostsCollection(mongoClient, cfg).Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "updatedAt", Value: 1}},
+				Options: options.Index().SetName(updatedAtIndexName),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			return GetPostsCollection(mongoClient, cfg).Indexes().DropOne(ctx, updatedAtIndexName)
+		},
+	},
+	{
+		Version:     7,
+		Description: "index webhook deliveries queue and expire delivered ones",
+		Up: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			_, err := GetWebhookDeliveriesCollection(mongoClient, cfg).Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+					Options: options.Index().SetName(dueIndexName),
+				},
+				{
+					Keys:    bson.D{{Key: "createdAt", Value: -1}},
+					Options: options.Index().SetName(createdAtIndexName),
+				},
+				{
+					// only delivered ones have expiresAt, dead ones are kept until redelivered
+					Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+					Options: options.Index().SetName(expiresAtIndexName).SetExpireAfterSeconds(0),
+				},
+			})
+			return err
+		},
+		Down: func(ctx context.Context, mongoClient *mongo.Client, cfg ClientConfig) error {
+			indexes := GetWebhookDeliveriesCollection(mongoClient, cfg).Indexes()
+			for _, name := range []string{dueIndexName, createdAtIndexName, expiresAtIndexName} {
+				if err := indexes.DropOne(ctx, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
@@ -0,0 +1,71 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/dbtest"
+)
+
+func TestRebuildTextIndex(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.Mongo(t)
+	cfg := db.ClientConfig{}
+	migrator, err := db.NewMigrator(mongoClient, cfg, db.Migrations)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(ctx))
+	indexes := db.GetPostsCollection(mongoClient, cfg).Indexes()
+
+	// index matching the definition is kept
+	rebuilt, err := db.RebuildTextIndex(ctx, mongoClient, cfg, false)
+	require.NoError(t, err)
+	assert.False(t, rebuilt)
+	rebuilt, err = db.RebuildTextIndex(ctx, mongoClient, cfg, true)
+	require.NoError(t, err)
+	assert.True(t, rebuilt)
+
+	// outdated index created under another name is replaced
+	require.NoError(t, indexes.DropOne(ctx, "title_text_content_text"))
+	_, err = indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "content", Value: "text"}},
+		Options: options.Index().SetName("search"),
+	})
+	require.NoError(t, err)
+	rebuilt, err = db.RebuildTextIndex(ctx, mongoClient, cfg, false)
+	require.NoError(t, err)
+	assert.True(t, rebuilt)
+	specs, err := indexes.ListSpecifications(ctx)
+	require.NoError(t, err)
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	assert.Contains(t, names, "title_text_content_text")
+	assert.NotContains(t, names, "search")
+	require.NoError(t, db.TextIndexCheck(db.GetPostsCollection(mongoClient, cfg))(ctx))
+}
+
+func TestMigrations_UncappedCacheInvalidations(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.Mongo(t)
+	cfg := db.ClientConfig{}
+	// replica started without migrations has published an invalidation
+	_, err := db.GetCacheInvalidationsCollection(mongoClient, cfg).InsertOne(ctx, bson.D{{Key: "origin", Value: "replica"}})
+	require.NoError(t, err)
+
+	migrator, err := db.NewMigrator(mongoClient, cfg, db.Migrations)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(ctx))
+
+	specs, err := mongoClient.Database(db.DefaultDatabase).ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: db.CacheInvalidationsCollection}})
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	assert.True(t, specs[0].Options.Lookup("capped").Boolean())
+}
@@ -0,0 +1,53 @@
+// Package dbtest runs mongodb in docker for tests of packages storing data in it
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/mineroot/news/internal/db"
+)
+
+// Mongo runs empty mongodb for the test, the test is skipped when docker is not available.
+// Env is set in the container, e.g. "TZ=Europe/Kyiv". Container is removed after the test
+func Mongo(t *testing.T, env ...string) *mongo.Client {
+	t.Helper()
+	pool, err := dockertest.NewPool("")
+	if err == nil {
+		err = pool.Client.Ping()
+	}
+	if err != nil {
+		t.Skipf("docker is not available: %v", err)
+	}
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "mongo",
+		Tag:        "8.0.9",
+		Env:        env,
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pool.Purge(resource) })
+
+	mongoClient, err := db.CreateMongoClient(context.Background(), db.ClientConfig{URI: fmt.Sprintf("mongodb://localhost:%s", resource.GetPort("27017/tcp"))})
+	require.NoError(t, err)
+	return mongoClient
+}
+
+// MigratedMongo is Mongo with all db.Migrations applied, collections are named by defaults of db.ClientConfig
+func MigratedMongo(t *testing.T, env ...string) *mongo.Client {
+	t.Helper()
+	mongoClient := Mongo(t, env...)
+	migrator, err := db.NewMigrator(mongoClient, db.ClientConfig{}, db.Migrations)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(context.Background()))
+	return mongoClient
+}
@@ -0,0 +1,92 @@
+package flash
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const cookieName = "flash"
+
+type Kind string
+
+const (
+	KindSuccess Kind = "success"
+	KindError   Kind = "error"
+)
+
+// Action is an optional button rendered next to the message, e.g. "Undo".
+// It is submitted as POST request to URL.
+type Action struct {
+	Label string `json:"l"`
+	URL   string `json:"u"`
+}
+
+type Message struct {
+	Kind   Kind    `json:"k"`
+	Text   string  `json:"t"`
+	Action *Action `json:"a,omitempty"`
+}
+
+func Success(text string) Message {
+	return Message{Kind: KindSuccess, Text: text}
+}
+
+func Error(text string) Message {
+	return Message{Kind: KindError, Text: text}
+}
+
+func (m Message) WithAction(label, url string) Message {
+	m.Action = &Action{Label: label, URL: url}
+	return m
+}
+
+// Set stores message in a short-lived cookie, so it survives exactly one redirect.
+func Set(c echo.Context, m Message) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     cookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(raw),
+		Path:     "/",
+		MaxAge:   60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Has reports whether there is a message to show, pages with it must not be served from cache
+func Has(c echo.Context) bool {
+	cookie, err := c.Cookie(cookieName)
+	return err == nil && cookie.Value != ""
+}
+
+// Pop returns the message stored by Set and removes the cookie, nil if there is no (valid) message.
+func Pop(c echo.Context) *Message {
+	cookie, err := c.Cookie(cookieName)
+	if err != nil {
+		return nil
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     cookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	raw, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	var m Message
+	if err := json.Unmarshal(raw, &m); err != nil || m.Text == "" {
+		return nil
+	}
+
+	return &m
+}
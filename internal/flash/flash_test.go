@@ -0,0 +1,56 @@
+package flash_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/flash"
+)
+
+func TestSetAndPop(t *testing.T) {
+	e := echo.New()
+
+	// set message
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+	flash.Set(c, flash.Success("Post deleted").WithAction("Undo", "/posts/1/restore"))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	// pop message on the next request
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	m := flash.Pop(c)
+	require.NotNil(t, m)
+	assert.Equal(t, flash.KindSuccess, m.Kind)
+	assert.Equal(t, "Post deleted", m.Text)
+	require.NotNil(t, m.Action)
+	assert.Equal(t, "Undo", m.Action.Label)
+	assert.Equal(t, "/posts/1/restore", m.Action.URL)
+
+	// cookie is expired after pop
+	cookies = rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Less(t, cookies[0].MaxAge, 0)
+}
+
+func TestPop_NoCookie(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.Nil(t, flash.Pop(c))
+}
+
+func TestPop_InvalidCookie(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "flash", Value: "not base64!"})
+	c := e.NewContext(req, httptest.NewRecorder())
+	assert.Nil(t, flash.Pop(c))
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/mineroot/news/internal/buildinfo"
+	"github.com/mineroot/news/internal/flash"
+	"github.com/mineroot/news/internal/middlewares"
+	"github.com/mineroot/news/internal/posts"
+)
+
+// pageCacheControl lets browsers keep pages, but revalidate them on every visit.
+// Pages carry per-client CSRF token, so shared caches must not store them
+const pageCacheControl = "private, no-cache"
+
+var build = buildinfo.Get()
+
+// pageETag is weak, as body differs in CSP nonce even when content is the same.
+// It depends on everything rendered on the page besides posts: app build, htmx partial or full page, CSRF token and query
+func pageETag(c echo.Context, postsOnPage []*posts.Post, extra ...any) string {
+	h := sha256.New()
+	csrfToken, _ := c.Get(middlewares.CSRFContextKey).(string)
+	_, _ = fmt.Fprintf(h, "%s|%s|%t|%s|%s|", build.Version, build.Commit, isPartial(c), csrfToken, c.QueryString())
+	for _, post := range postsOnPage {
+		_, _ = fmt.Fprintf(h, "%s|%d|", post.ID.Hex(), post.Updated.UnixNano())
+	}
+	_, _ = fmt.Fprint(h, extra...)
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets caching headers and reports whether client's copy is fresh, so 304 must be sent.
+// If-None-Match takes precedence over If-Modified-Since as RFC 9110 requires, zero modified time disables the latter
+func notModified(c echo.Context, etag string, modified time.Time) bool {
+	h := c.Response().Header()
+	h.Add(echo.HeaderVary, "HX-Request, HX-History-Restore-Request")
+	// flash message is shown only once
+	if flash.Has(c) {
+		h.Set(echo.HeaderCacheControl, "no-store")
+		return false
+	}
+	h.Set(echo.HeaderCacheControl, pageCacheControl)
+	h.Set("ETag", etag)
+	if !modified.IsZero() {
+		h.Set(echo.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+
+	req := c.Request()
+	fresh := false
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		fresh = etagMatches(inm, etag)
+	} else if ims, err := http.ParseTime(req.Header.Get("If-Modified-Since")); err == nil && !modified.IsZero() {
+		fresh = !modified.Truncate(time.Second).After(ims)
+	}
+	if fresh {
+		// cached body has nonce of the original response, new policy would block its scripts
+		h.Del("Content-Security-Policy")
+		h.Del("Content-Security-Policy-Report-Only")
+	}
+	return fresh
+}
+
+// etagMatches uses weak comparison of If-None-Match list
+func etagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/flash"
+	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/posts"
+)
+
+func TestViewPostHandler_ConditionalGet(t *testing.T) {
+	e := echo.New()
+	updated := time.Date(2025, 5, 1, 12, 0, 0, 500, time.UTC)
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "Title", Updated: updated}
+	m := NewMockPostFinder(t)
+	m.EXPECT().FindById(mock.Anything, post.ID).Return(post, nil)
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/posts/"+post.ID.Hex(), nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Security-Policy", "script-src 'nonce-new'")
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(post.ID.Hex())
+		require.NoError(t, handlers.ViewPostHandler(m)(c))
+		return rec
+	}
+
+	rec := get(nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, "Thu, 01 May 2025 12:00:00 GMT", rec.Header().Get(echo.HeaderLastModified))
+	assert.Equal(t, "private, no-cache", rec.Header().Get(echo.HeaderCacheControl))
+	assert.Contains(t, rec.Header().Values(echo.HeaderVary), "HX-Request, HX-History-Restore-Request")
+
+	// fresh copy, cached page keeps its own policy
+	rec = get(map[string]string{"If-None-Match": `"other", ` + etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"))
+	rec = get(map[string]string{"If-Modified-Since": "Thu, 01 May 2025 12:00:00 GMT"})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// stale copy
+	rec = get(map[string]string{"If-None-Match": `W/"other"`, "If-Modified-Since": "Thu, 01 May 2025 12:00:00 GMT"})
+	assert.Equal(t, http.StatusOK, rec.Code, "If-None-Match takes precedence")
+	rec = get(map[string]string{"If-Modified-Since": "Thu, 01 May 2025 11:59:59 GMT"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Content-Security-Policy"))
+
+	// partial page is a different representation
+	rec = get(map[string]string{"HX-Request": "true", "If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	// updated post
+	post.Updated = updated.Add(time.Second)
+	rec = get(map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestViewPostHandler_FlashIsNotCached(t *testing.T) {
+	e := echo.New()
+	post := &posts.Post{ID: bson.NewObjectID(), Updated: time.Now()}
+	m := NewMockPostFinder(t)
+	m.EXPECT().FindById(mock.Anything, post.ID).Return(post, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/"+post.ID.Hex(), nil)
+	prev := httptest.NewRecorder()
+	flash.Set(e.NewContext(req, prev), flash.Success("Post saved"))
+	req.AddCookie(prev.Result().Cookies()[0])
+	req.Header.Set("If-None-Match", "*")
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(post.ID.Hex())
+	require.NoError(t, handlers.ViewPostHandler(m)(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get(echo.HeaderCacheControl))
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestViewHomeHandler_ConditionalGet(t *testing.T) {
+	e := echo.New()
+	post := &posts.Post{ID: bson.NewObjectID(), Updated: time.Now()}
+	total := paging.Total{Items: 1}
+	m := NewMockPostsPaginator(t)
+	m.EXPECT().
+		FindAllByQueryWithPagination(mock.Anything, mock.Anything, "").
+		RunAndReturn(func(_ context.Context, _ *paging.Paginator, _ string) ([]*posts.Post, paging.Total, error) {
+			return []*posts.Post{post}, total, nil
+		})
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rec := httptest.NewRecorder()
+		require.NoError(t, handlers.ViewHomeHandler(m, 4)(e.NewContext(req, rec)))
+		return rec
+	}
+
+	etag := get("").Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, get(etag).Code)
+
+	// post is added to another page
+	total = paging.Total{Items: 5}
+	assert.Equal(t, http.StatusOK, get(etag).Code)
+}
+
+func TestViewHomeHandler_DeletedPost(t *testing.T) {
+	e := echo.New()
+	now := time.Now()
+	page := []*posts.Post{
+		{ID: bson.NewObjectID(), Updated: now},
+		{ID: bson.NewObjectID(), Updated: now.Add(-time.Hour)},
+	}
+	m := NewMockPostsPaginator(t)
+	m.EXPECT().
+		FindAllByQueryWithPagination(mock.Anything, mock.Anything, "").
+		RunAndReturn(func(_ context.Context, _ *paging.Paginator, _ string) ([]*posts.Post, paging.Total, error) {
+			return page, paging.Total{Items: len(page)}, nil
+		})
+
+	get := func(ifModifiedSince string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-Modified-Since", ifModifiedSince)
+		rec := httptest.NewRecorder()
+		require.NoError(t, handlers.ViewHomeHandler(m, 4)(e.NewContext(req, rec)))
+		return rec
+	}
+
+	rec := get("")
+	assert.Empty(t, rec.Header().Get(echo.HeaderLastModified))
+
+	// the older post is deleted, latest update on page stays the same
+	page = page[:1]
+	rec = get(now.Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), page[0].ID.Hex())
+}
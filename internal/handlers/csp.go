@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// maxCSPReportSize limits report body, browsers send a few kilobytes at most
+const maxCSPReportSize = 64 << 10
+
+// maxCSPViolations limits logged violations of a single report, the rest are only counted,
+// so a crafted report can't flood logs
+const maxCSPViolations = 5
+
+// cspReport is sent by browsers to report-uri as application/csp-report
+type cspReport struct {
+	Body struct {
+		DocumentURI        string `json:"document-uri"`
+		EffectiveDirective string `json:"effective-directive"`
+		ViolatedDirective  string `json:"violated-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is sent by browsers to report-to endpoint as application/reports+json
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+type cspViolation struct {
+	document    string
+	directive   string
+	blocked     string
+	source      string
+	line        int
+	disposition string
+}
+
+// CSPReportHandler logs Content-Security-Policy violations reported by browsers
+func CSPReportHandler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxCSPReportSize))
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+		violations, err := parseCSPReport(c.Request().Header.Get(echo.HeaderContentType), body)
+		if err != nil {
+			return echo.ErrBadRequest
+		}
+
+		logger := zerolog.Ctx(c.Request().Context())
+		if dropped := len(violations) - maxCSPViolations; dropped > 0 {
+			violations = violations[:maxCSPViolations]
+			defer logger.Warn().Int("dropped", dropped).Msg("too many csp violations in report")
+		}
+		for _, v := range violations {
+			logger.Warn().
+				Str("document", v.document).
+				Str("directive", v.directive).
+				Str("blocked", v.blocked).
+				Str("source", v.source).
+				Int("line", v.line).
+				Str("disposition", v.disposition).
+				Msg("csp violation")
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+func parseCSPReport(contentType string, body []byte) ([]cspViolation, error) {
+	if strings.HasPrefix(contentType, "application/reports+json") {
+		var reports []reportingAPIReport
+		if err := json.Unmarshal(body, &reports); err != nil {
+			return nil, err
+		}
+		var violations []cspViolation
+		for _, r := range reports {
+			if r.Type != "csp-violation" {
+				continue
+			}
+			violations = append(violations, cspViolation{
+				document:    r.Body.DocumentURL,
+				directive:   r.Body.EffectiveDirective,
+				blocked:     r.Body.BlockedURL,
+				source:      r.Body.SourceFile,
+				line:        r.Body.LineNumber,
+				disposition: r.Body.Disposition,
+			})
+		}
+		return violations, nil
+	}
+
+	var report cspReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, err
+	}
+	directive := report.Body.EffectiveDirective
+	if directive == "" {
+		directive = report.Body.ViolatedDirective
+	}
+	return []cspViolation{{
+		document:    report.Body.DocumentURI,
+		directive:   directive,
+		blocked:     report.Body.BlockedURI,
+		source:      report.Body.SourceFile,
+		line:        report.Body.LineNumber,
+		disposition: report.Body.Disposition,
+	}}, nil
+}
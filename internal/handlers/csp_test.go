@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/handlers"
+)
+
+func TestCSPReportHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		contentType   string
+		body          string
+		expectedCode  int
+		expectedBlock []string
+	}{
+		{
+			"report-uri",
+			"application/csp-report",
+			`{"csp-report":{"document-uri":"https://example.com/","violated-directive":"script-src","blocked-uri":"inline","line-number":3}}`,
+			http.StatusNoContent,
+			[]string{"inline"},
+		},
+		{
+			"reporting api",
+			"application/reports+json",
+			`[{"type":"csp-violation","body":{"documentURL":"https://example.com/","effectiveDirective":"style-src-elem","blockedURL":"https://cdn.example.com/x.css"}},{"type":"deprecation","body":{}}]`,
+			http.StatusNoContent,
+			[]string{"https://cdn.example.com/x.css"},
+		},
+		{"invalid json", "application/csp-report", `{`, http.StatusBadRequest, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := zerolog.New(&logs)
+			req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			req = req.WithContext(logger.WithContext(req.Context()))
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			err := handlers.CSPReportHandler()(c)
+			if tc.expectedCode != http.StatusNoContent {
+				assert.ErrorIs(t, err, echo.ErrBadRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+
+			var blocked []string
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &entry))
+				assert.Equal(t, "csp violation", entry["message"])
+				blocked = append(blocked, entry["blocked"].(string))
+			}
+			assert.Equal(t, tc.expectedBlock, blocked)
+		})
+	}
+}
+
+func TestCSPReportHandler_TooManyViolations(t *testing.T) {
+	var reports []string
+	for range 20 {
+		reports = append(reports, `{"type":"csp-violation","body":{"blockedURL":"inline"}}`)
+	}
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+	req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader("["+strings.Join(reports, ",")+"]"))
+	req.Header.Set(echo.HeaderContentType, "application/reports+json")
+	req = req.WithContext(logger.WithContext(req.Context()))
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, handlers.CSPReportHandler()(echo.New().NewContext(req, rec)))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, 5, strings.Count(logs.String(), `"message":"csp violation"`))
+	assert.Contains(t, lines[5], `"dropped":15`)
+}
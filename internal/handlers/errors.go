@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/route"
+	"github.com/mineroot/news/internal/webhooks"
+	"github.com/mineroot/news/templates"
+)
+
+const mimeApplicationProblemJSON = "application/problem+json"
+
+type errorPage struct {
+	title  string
+	detail string
+}
+
+var errorPages = map[int]errorPage{
+	http.StatusBadRequest:            {"Bad request", "The request could not be understood by the server."},
+	http.StatusForbidden:             {"Forbidden", "You are not allowed to perform this action."},
+	http.StatusNotFound:              {"Page not found", "The page you are looking for does not exist or has been removed."},
+	http.StatusMethodNotAllowed:      {"Method not allowed", "This page does not support the requested method."},
+	http.StatusConflict:              {"Conflict", "The request conflicts with the current state of the resource."},
+	http.StatusRequestEntityTooLarge: {"Request too large", "The submitted data is too large."},
+	http.StatusUnsupportedMediaType:  {"Unsupported media type", "The submitted data has unsupported format."},
+	http.StatusUnprocessableEntity:   {"Invalid data", "The submitted data is invalid."},
+	http.StatusTooManyRequests:       {"Too many requests", "You are sending requests too fast. Please try again later."},
+	http.StatusInternalServerError:   {"Internal server error", "Something went wrong on our side. Please try again later."},
+	http.StatusServiceUnavailable:    {"Service unavailable", "The service is temporarily unavailable. Please try again later."},
+}
+
+// problem is RFC 9457 problem details object
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+func ErrorHandler(logger *zerolog.Logger) func(err error, c echo.Context) {
+	return errorHandler(logger, render, route.ViewHome)
+}
+
+// AdminErrorHandler is ErrorHandler of admin server, error pages use its layout
+func AdminErrorHandler(logger *zerolog.Logger) func(err error, c echo.Context) {
+	return errorHandler(logger, renderAdmin, route.ViewWebhookDeliveries)
+}
+
+func errorHandler(
+	logger *zerolog.Logger,
+	render func(c echo.Context, statusCode int, t templ.Component, title string) error,
+	home string,
+) func(err error, c echo.Context) {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		code, page := resolveError(err)
+		if code >= http.StatusInternalServerError {
+			// prefer request scoped logger, it carries request_id
+			l := zerolog.Ctx(c.Request().Context())
+			if l.GetLevel() == zerolog.Disabled {
+				l = logger
+			}
+			l.Error().
+				Str("uri", c.Request().RequestURI).
+				Int("status", code).
+				Err(err).
+				Msg("server error")
+		}
+
+		switch {
+		case c.Request().Method == http.MethodHead:
+			_ = c.NoContent(code)
+		case acceptsJSON(c.Request()):
+			c.Response().Header().Set(echo.HeaderContentType, mimeApplicationProblemJSON)
+			_ = c.JSON(code, problem{
+				Type:     "about:blank",
+				Title:    page.title,
+				Status:   code,
+				Detail:   page.detail,
+				Instance: c.Request().URL.Path,
+			})
+		default:
+			if c.Request().Header.Get("HX-Request") == "true" {
+				// show error inline in place of the main content during partial navigation
+				c.Response().Header().Set("HX-Retarget", "#main")
+				c.Response().Header().Set("HX-Reswap", "innerHTML")
+				c.Response().Header().Set("HX-Push-Url", "false")
+			}
+			_ = render(c, code, templates.HttpError(code, page.title, page.detail, home), page.title)
+		}
+	}
+}
+
+// resolveError maps err to HTTP status code and the page describing it
+func resolveError(err error) (int, errorPage) {
+	code := http.StatusInternalServerError
+	var he *echo.HTTPError
+	switch {
+	case errors.As(err, &he) && he.Code >= http.StatusBadRequest && he.Code <= 599:
+		code = he.Code
+	case errors.Is(err, posts.ErrNotFound), errors.Is(err, webhooks.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, posts.ErrConflict):
+		code = http.StatusConflict
+	case errors.Is(err, posts.ErrValidation):
+		code = http.StatusUnprocessableEntity
+	case errors.Is(err, posts.ErrUnavailable):
+		code = http.StatusServiceUnavailable
+	}
+
+	page, ok := errorPages[code]
+	if !ok {
+		page = errorPage{title: http.StatusText(code)}
+	}
+	// client errors may carry a human-readable explanation, server errors never leak details
+	if he != nil && code < http.StatusInternalServerError {
+		if msg, ok := he.Message.(string); ok && msg != "" && msg != http.StatusText(code) {
+			page.detail = msg
+		}
+	}
+	var ve *posts.ValidationError
+	if errors.As(err, &ve) && len(ve.Fields) > 0 {
+		page.detail = ve.Error()
+	}
+
+	return code, page
+}
+
+// acceptsJSON reports whether the client prefers JSON over HTML
+func acceptsJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get(echo.HeaderAccept), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch {
+		case mediaType == echo.MIMETextHTML:
+			return false
+		case mediaType == echo.MIMEApplicationJSON, strings.HasSuffix(mediaType, "+json"):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+func TestErrorHandler(t *testing.T) {
+	e := echo.New()
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+	errorHandler := handlers.ErrorHandler(&logger)
+
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedBody string
+		expectedLog  bool
+	}{
+		{"not found", echo.ErrNotFound, http.StatusNotFound, "Page not found", false},
+		{"method not allowed", echo.ErrMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed", false},
+		{"bind error", echo.NewHTTPError(http.StatusBadRequest, "Syntax error"), http.StatusBadRequest, "Syntax error", false},
+		{"body too large", echo.ErrStatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "Request too large", false},
+		{"rate limited", echo.ErrTooManyRequests, http.StatusTooManyRequests, "Too many requests", false},
+		{"unknown error", errors.New("boom"), http.StatusInternalServerError, "Internal server error", true},
+		{"unavailable", echo.ErrServiceUnavailable, http.StatusServiceUnavailable, "Service unavailable", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			errorHandler(tc.err, c)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+			assert.Equal(t, tc.expectedLog, logs.Len() > 0)
+		})
+	}
+}
+
+func TestErrorHandler_ServerErrorDetailsAreHidden(t *testing.T) {
+	e := echo.New()
+	logger := zerolog.Nop()
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	handlers.ErrorHandler(&logger)(echo.NewHTTPError(http.StatusInternalServerError, "secret details"), c)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "secret details")
+}
+
+func TestErrorHandler_ProblemJSON(t *testing.T) {
+	e := echo.New()
+	logger := zerolog.Nop()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/posts/123", nil)
+	req.Header.Set(echo.HeaderAccept, "application/json")
+	c := e.NewContext(req, rec)
+	handlers.ErrorHandler(&logger)(echo.ErrNotFound, c)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get(echo.HeaderContentType))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Page not found",
+		"status": 404,
+		"detail": "The page you are looking for does not exist or has been removed.",
+		"instance": "/posts/123"
+	}`, rec.Body.String())
+
+	// html is preferred
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/posts/123", nil)
+	req.Header.Set(echo.HeaderAccept, "text/html,application/json")
+	c = e.NewContext(req, rec)
+	handlers.ErrorHandler(&logger)(echo.ErrNotFound, c)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), echo.MIMETextHTML)
+}
+
+func TestErrorHandler_Htmx(t *testing.T) {
+	e := echo.New()
+	logger := zerolog.Nop()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/posts/123", nil)
+	req.Header.Set("HX-Request", "true")
+	c := e.NewContext(req, rec)
+	handlers.ErrorHandler(&logger)(echo.ErrNotFound, c)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "#main", rec.Header().Get("HX-Retarget"))
+	assert.Equal(t, "innerHTML", rec.Header().Get("HX-Reswap"))
+	assert.Equal(t, "false", rec.Header().Get("HX-Push-Url"))
+}
+
+func TestErrorHandler_DomainErrors(t *testing.T) {
+	e := echo.New()
+	logger := zerolog.Nop()
+
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{"not found", posts.ErrNotFound, http.StatusNotFound, "Page not found"},
+		{"webhook not found", webhooks.ErrNotFound, http.StatusNotFound, "Page not found"},
+		{"conflict", fmt.Errorf("%w: duplicate key", posts.ErrConflict), http.StatusConflict, "Conflict"},
+		{"validation", &posts.ValidationError{Fields: []posts.FieldError{{Field: "title", Message: "is required"}}}, http.StatusUnprocessableEntity, "&#39;title&#39; is required"},
+		{"unavailable", fmt.Errorf("%w: timeout", posts.ErrUnavailable), http.StatusServiceUnavailable, "Service unavailable"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			handlers.ErrorHandler(&logger)(tc.err, c)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+		})
+	}
+}
+
+func TestAdminErrorHandler(t *testing.T) {
+	e := echo.New()
+	logger := zerolog.Nop()
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/webhooks/deliveries/1/redeliver", nil), rec)
+	handlers.AdminErrorHandler(&logger)(webhooks.ErrNotFound, c)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "Page not found")
+	assert.Contains(t, body, `href="/webhooks/deliveries"`)
+	assert.NotContains(t, body, "htmx")
+}
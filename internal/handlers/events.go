@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+
+	"github.com/mineroot/news/internal/live"
+	"github.com/mineroot/news/templates"
+)
+
+// EventsHandler streams posts changes as server-sent events for htmx sse extension:
+// "new-posts" counts posts created since connection, "title-ID" and "post-ID" replace edited or deleted post.
+// Comment is sent every heartbeat, so proxies keep idle connection open
+func EventsHandler(hub *live.Hub, heartbeat time.Duration) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		sub := hub.Subscribe()
+		defer hub.Unsubscribe(sub)
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set(echo.HeaderCacheControl, "no-store")
+		// disable nginx buffering
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+		res.Flush()
+
+		ctx := c.Request().Context()
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		newPosts := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+			case event, ok := <-sub.Events():
+				if !ok {
+					// client reconnects, e.g. to another replica
+					return nil
+				}
+				if event.Kind == live.Created {
+					newPosts++
+				}
+				if err := writeEvent(ctx, c, res, event, newPosts); err != nil {
+					return nil
+				}
+			}
+			res.Flush()
+		}
+	}
+}
+
+// writeEvent writes event in the format of htmx sse extension, data of all events is HTML
+func writeEvent(ctx context.Context, c echo.Context, res *echo.Response, event live.Event, newPosts int) error {
+	id := event.Post.ID.Hex()
+	switch event.Kind {
+	case live.Created:
+		return writeSSE(ctx, res, "new-posts", templates.NewPostsBanner(c.Echo().Reverse, newPosts))
+	case live.Updated:
+		if err := writeSSE(ctx, res, "title-"+id, templ.Raw(templ.EscapeString(event.Post.Title))); err != nil {
+			return err
+		}
+		return writeSSE(ctx, res, "post-"+id, templates.PostArticle(c.Echo().Reverse, event.Post))
+	case live.Deleted:
+		return writeSSE(ctx, res, "post-"+id, templates.PostDeleted())
+	}
+	return nil
+}
+
+func writeSSE(ctx context.Context, res *echo.Response, name string, data templ.Component) error {
+	var buf bytes.Buffer
+	if err := data.Render(ctx, &buf); err != nil {
+		return err
+	}
+	var event strings.Builder
+	event.WriteString("event: " + name + "\n")
+	for line := range strings.Lines(buf.String()) {
+		event.WriteString("data: " + strings.TrimSuffix(line, "\n") + "\n")
+	}
+	event.WriteString("\n")
+	_, err := res.Write([]byte(event.String()))
+	return err
+}
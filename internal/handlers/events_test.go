@@ -0,0 +1,201 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/net/html"
+
+	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/live"
+	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/route"
+	"github.com/mineroot/news/templates"
+)
+
+func TestEventsHandler(t *testing.T) {
+	e := echo.New()
+	e.GET(route.ViewHome, nil).Name = route.ViewHome
+	hub := live.NewHub(10)
+	e.GET(route.Events, handlers.EventsHandler(hub, 50*time.Millisecond))
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + route.Events)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get(echo.HeaderContentType))
+	assert.Equal(t, "no-store", res.Header.Get(echo.HeaderCacheControl))
+	stream := bufio.NewReader(res.Body)
+	// readEvent reads lines of the next event or comment
+	readEvent := func() string {
+		var event strings.Builder
+		for {
+			line, err := stream.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return event.String()
+			}
+			event.WriteString(line)
+		}
+	}
+
+	assert.Equal(t, ": heartbeat\n", readEvent())
+
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "<b>Title</b>", Content: "Content"}
+	hub.Publish(live.Event{Kind: live.Created, Post: post})
+	hub.Publish(live.Event{Kind: live.Created, Post: post})
+	hub.Publish(live.Event{Kind: live.Updated, Post: post})
+	hub.Publish(live.Event{Kind: live.Deleted, Post: &posts.Post{ID: post.ID}})
+
+	event := nextEvent(readEvent)
+	assert.True(t, strings.HasPrefix(event, "event: new-posts\ndata: "))
+	assert.Contains(t, event, "1 new story")
+	event = nextEvent(readEvent)
+	assert.Contains(t, event, "2 new stories")
+	assert.Contains(t, event, `href="/"`)
+
+	assert.Equal(t, "event: title-"+post.ID.Hex()+"\ndata: &lt;b&gt;Title&lt;/b&gt;\n", nextEvent(readEvent))
+	event = nextEvent(readEvent)
+	assert.True(t, strings.HasPrefix(event, "event: post-"+post.ID.Hex()+"\ndata: "))
+	assert.Contains(t, event, "&lt;b&gt;Title&lt;/b&gt;")
+	for line := range strings.Lines(event) {
+		assert.Regexp(t, `^(event|data): `, line)
+	}
+	event = nextEvent(readEvent)
+	assert.True(t, strings.HasPrefix(event, "event: post-"+post.ID.Hex()+"\ndata: "))
+	assert.Contains(t, event, "This post has been deleted")
+}
+
+func TestEventsHandler_HubClosed(t *testing.T) {
+	e := echo.New()
+	hub := live.NewHub(10)
+	e.GET(route.Events, handlers.EventsHandler(hub, time.Minute))
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + route.Events)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- hub.Run(ctx, idleSource{}) }()
+	cancel()
+	require.NoError(t, <-done)
+
+	// stream ends, so server shutdown does not wait for it
+	_, err = bufio.NewReader(res.Body).ReadString('\n')
+	assert.Error(t, err)
+}
+
+// nextEvent skips heartbeats
+func nextEvent(readEvent func() string) string {
+	for {
+		if event := readEvent(); event != ": heartbeat\n" {
+			return event
+		}
+	}
+}
+
+type idleSource struct{}
+
+func (idleSource) Watch(ctx context.Context, _ func(live.Event)) error {
+	<-ctx.Done()
+	return nil
+}
+
+// TestLiveTargets checks that links and buttons inside live updated elements still target #main,
+// while those elements target themselves
+func TestLiveTargets(t *testing.T) {
+	e := echo.New()
+	for _, r := range []string{route.ViewHome, route.ViewPost, route.ViewUpdatePostForm, route.DeletePost, route.Events} {
+		e.GET(r, nil).Name = r
+	}
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "Title", Content: "Content"}
+	finder := NewMockPostFinder(t)
+	finder.EXPECT().FindById(mock.Anything, post.ID).Return(post, nil)
+	paginator := NewMockPostsPaginator(t)
+	paginator.EXPECT().
+		FindAllByQueryWithPagination(mock.Anything, mock.Anything, mock.Anything).
+		Return([]*posts.Post{post}, paging.Total{Items: 1}, nil)
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/posts/"+post.ID.Hex(), nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(post.ID.Hex())
+	require.NoError(t, handlers.ViewPostHandler(finder)(c))
+	assertLiveTargets(t, rec.Body.String(), 1)
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	require.NoError(t, handlers.ViewHomeHandler(paginator, 4)(c))
+	// banner is swapped into the empty slot by "new-posts" event
+	var banner bytes.Buffer
+	require.NoError(t, templates.NewPostsBanner(e.Reverse, 3).Render(context.Background(), &banner))
+	slot := `hx-disinherit="hx-target"></div>`
+	require.Contains(t, rec.Body.String(), slot)
+	page := strings.Replace(rec.Body.String(), slot, `hx-disinherit="hx-target">`+banner.String()+`</div>`, 1)
+	assertLiveTargets(t, page, 2)
+}
+
+// assertLiveTargets resolves hx-target of every element like htmx does, requests must target #main
+// and sse-swap elements must target themselves
+func assertLiveTargets(t *testing.T, page string, swaps int) {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(page))
+	require.NoError(t, err)
+	attr := func(n *html.Node, key string) (string, bool) {
+		for _, a := range n.Attr {
+			if a.Key == key {
+				return a.Val, true
+			}
+		}
+		return "", false
+	}
+	target := func(n *html.Node) string {
+		if v, ok := attr(n, "hx-target"); ok {
+			return v
+		}
+		for p := n.Parent; p != nil; p = p.Parent {
+			v, ok := attr(p, "hx-target")
+			disinherit, _ := attr(p, "hx-disinherit")
+			if ok && disinherit != "*" && !strings.Contains(disinherit, "hx-target") {
+				return v
+			}
+		}
+		return ""
+	}
+	requests, found := 0, 0
+	for n := range doc.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if _, ok := attr(n, "sse-swap"); ok {
+			found++
+			assert.Equal(t, "this", target(n))
+		}
+		_, get := attr(n, "hx-get")
+		_, post := attr(n, "hx-post")
+		if get || post {
+			requests++
+			var b strings.Builder
+			_ = html.Render(&b, n)
+			assert.Equal(t, "#main", target(n), b.String())
+		}
+	}
+	assert.Equal(t, swaps, found)
+	assert.NotZero(t, requests)
+}
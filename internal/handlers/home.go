@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -10,9 +11,8 @@ import (
 	"github.com/mineroot/news/templates"
 )
 
-func ViewHomeHandler(repo PostsPaginator) echo.HandlerFunc {
+func ViewHomeHandler(repo PostsPaginator, pageSize int) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		const pageSize = 4
 		paginator, err := paging.NewPaginator(c.QueryParam("page"), pageSize)
 		if err != nil {
 			return c.Redirect(http.StatusTemporaryRedirect, "/")
@@ -23,10 +23,14 @@ func ViewHomeHandler(repo PostsPaginator) echo.HandlerFunc {
 			return err
 		}
 
-		paginator.SetRealItemsCount(total)
+		paginator.SetTotal(total)
 		if paginator.NeedsRedirect() {
 			return c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("/?page=%d", paginator.Page()))
 		}
+		// listing has no Last-Modified, deleted post changes it without moving updates of posts forward
+		if notModified(c, pageETag(c, all, total), time.Time{}) {
+			return c.NoContent(http.StatusNotModified)
+		}
 
 		return render(c, http.StatusOK, templates.Home(c.Echo().Reverse, all, paginator, ""), "Home")
 	}
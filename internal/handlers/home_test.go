@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/paging"
 	"github.com/mineroot/news/internal/posts"
 )
 
@@ -21,25 +22,38 @@ func TestViewHomeHandler(t *testing.T) {
 	m := NewMockPostsPaginator(t)
 	m.EXPECT().
 		FindAllByQueryWithPagination(mock.Anything, mock.Anything, mock.Anything).
-		Return([]*posts.Post{post, post, post, post}, 10, nil)
+		Return([]*posts.Post{post, post, post, post}, paging.Total{Items: 10}, nil)
 
 	// success
 	rec := httptest.NewRecorder()
 	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
-	require.NoError(t, handlers.ViewHomeHandler(m)(c))
+	require.NoError(t, handlers.ViewHomeHandler(m, 4)(c))
 	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<html")
+	assert.Contains(t, rec.Body.String(), `hx-ext="sse"`)
+	assert.Contains(t, rec.Body.String(), `sse-swap="new-posts"`)
+
+	// htmx navigation gets main content only
+	req := httptest.NewRequest(http.MethodGet, "/?page=2", nil)
+	req.Header.Set("HX-Request", "true")
+	rec = httptest.NewRecorder()
+	require.NoError(t, handlers.ViewHomeHandler(m, 4)(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "<html")
+	assert.Contains(t, rec.Body.String(), `<div id="main"`)
+	assert.Contains(t, rec.Body.String(), `href="?page=3"`)
 
 	// page is too large
 	rec = httptest.NewRecorder()
 	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/?page=99", nil), rec)
-	require.NoError(t, handlers.ViewHomeHandler(m)(c))
+	require.NoError(t, handlers.ViewHomeHandler(m, 4)(c))
 	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
 	assert.Equal(t, "/?page=3", rec.Header().Get("Location")) // redirect to the max allowed page
 
 	// page is invalid
 	rec = httptest.NewRecorder()
 	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/?page=first", nil), rec)
-	require.NoError(t, handlers.ViewHomeHandler(m)(c))
+	require.NoError(t, handlers.ViewHomeHandler(m, 4)(c))
 	assert.Equal(t, http.StatusTemporaryRedirect, rec.Code)
 	assert.Equal(t, "/", rec.Header().Get("Location")) // redirect to first page
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mineroot/news/internal/paging"
 	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/webhooks"
 )
 
 type PostsPaginator interface {
@@ -14,7 +15,7 @@ type PostsPaginator interface {
 		ctx context.Context,
 		paginator *paging.Paginator,
 		query string,
-	) ([]*posts.Post, int, error)
+	) ([]*posts.Post, paging.Total, error)
 }
 
 type PostFinder interface {
@@ -32,3 +33,15 @@ type PostUpdater interface {
 type PostDeleter interface {
 	DeleteById(ctx context.Context, id bson.ObjectID) (*posts.Post, error)
 }
+
+type PostRestorer interface {
+	RestoreById(ctx context.Context, id bson.ObjectID) (*posts.Post, error)
+}
+
+type WebhookDeliveryFinder interface {
+	FindDeliveries(ctx context.Context, status webhooks.Status, limit int) ([]*webhooks.Delivery, error)
+}
+
+type WebhookRedeliverer interface {
+	Redeliver(ctx context.Context, id bson.ObjectID) (*webhooks.Delivery, error)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/mineroot/news/internal/paging"
 	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/webhooks"
 	mock "github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -41,7 +42,7 @@ func (_m *MockPostsPaginator) EXPECT() *MockPostsPaginator_Expecter {
 }
 
 // FindAllByQueryWithPagination provides a mock function for the type MockPostsPaginator
-func (_mock *MockPostsPaginator) FindAllByQueryWithPagination(ctx context.Context, paginator *paging.Paginator, query string) ([]*posts.Post, int, error) {
+func (_mock *MockPostsPaginator) FindAllByQueryWithPagination(ctx context.Context, paginator *paging.Paginator, query string) ([]*posts.Post, paging.Total, error) {
 	ret := _mock.Called(ctx, paginator, query)
 
 	if len(ret) == 0 {
@@ -49,9 +50,9 @@ func (_mock *MockPostsPaginator) FindAllByQueryWithPagination(ctx context.Contex
 	}
 
 	var r0 []*posts.Post
-	var r1 int
+	var r1 paging.Total
 	var r2 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, *paging.Paginator, string) ([]*posts.Post, int, error)); ok {
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *paging.Paginator, string) ([]*posts.Post, paging.Total, error)); ok {
 		return returnFunc(ctx, paginator, query)
 	}
 	if returnFunc, ok := ret.Get(0).(func(context.Context, *paging.Paginator, string) []*posts.Post); ok {
@@ -61,10 +62,10 @@ func (_mock *MockPostsPaginator) FindAllByQueryWithPagination(ctx context.Contex
 			r0 = ret.Get(0).([]*posts.Post)
 		}
 	}
-	if returnFunc, ok := ret.Get(1).(func(context.Context, *paging.Paginator, string) int); ok {
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *paging.Paginator, string) paging.Total); ok {
 		r1 = returnFunc(ctx, paginator, query)
 	} else {
-		r1 = ret.Get(1).(int)
+		r1 = ret.Get(1).(paging.Total)
 	}
 	if returnFunc, ok := ret.Get(2).(func(context.Context, *paging.Paginator, string) error); ok {
 		r2 = returnFunc(ctx, paginator, query)
@@ -94,12 +95,12 @@ func (_c *MockPostsPaginator_FindAllByQueryWithPagination_Call) Run(run func(ctx
 	return _c
 }
 
-func (_c *MockPostsPaginator_FindAllByQueryWithPagination_Call) Return(posts1 []*posts.Post, n int, err error) *MockPostsPaginator_FindAllByQueryWithPagination_Call {
-	_c.Call.Return(posts1, n, err)
+func (_c *MockPostsPaginator_FindAllByQueryWithPagination_Call) Return(posts1 []*posts.Post, total paging.Total, err error) *MockPostsPaginator_FindAllByQueryWithPagination_Call {
+	_c.Call.Return(posts1, total, err)
 	return _c
 }
 
-func (_c *MockPostsPaginator_FindAllByQueryWithPagination_Call) RunAndReturn(run func(ctx context.Context, paginator *paging.Paginator, query string) ([]*posts.Post, int, error)) *MockPostsPaginator_FindAllByQueryWithPagination_Call {
+func (_c *MockPostsPaginator_FindAllByQueryWithPagination_Call) RunAndReturn(run func(ctx context.Context, paginator *paging.Paginator, query string) ([]*posts.Post, paging.Total, error)) *MockPostsPaginator_FindAllByQueryWithPagination_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -441,3 +442,256 @@ func (_c *MockPostDeleter_DeleteById_Call) RunAndReturn(run func(ctx context.Con
 	_c.Call.Return(run)
 	return _c
 }
+
+// NewMockPostRestorer creates a new instance of MockPostRestorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockPostRestorer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockPostRestorer {
+	mock := &MockPostRestorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockPostRestorer is an autogenerated mock type for the PostRestorer type
+type MockPostRestorer struct {
+	mock.Mock
+}
+
+type MockPostRestorer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockPostRestorer) EXPECT() *MockPostRestorer_Expecter {
+	return &MockPostRestorer_Expecter{mock: &_m.Mock}
+}
+
+// RestoreById provides a mock function for the type MockPostRestorer
+func (_mock *MockPostRestorer) RestoreById(ctx context.Context, id bson.ObjectID) (*posts.Post, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreById")
+	}
+
+	var r0 *posts.Post
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, bson.ObjectID) (*posts.Post, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, bson.ObjectID) *posts.Post); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*posts.Post)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, bson.ObjectID) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockPostRestorer_RestoreById_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreById'
+type MockPostRestorer_RestoreById_Call struct {
+	*mock.Call
+}
+
+// RestoreById is a helper method to define mock.On call
+//   - ctx
+//   - id
+func (_e *MockPostRestorer_Expecter) RestoreById(ctx interface{}, id interface{}) *MockPostRestorer_RestoreById_Call {
+	return &MockPostRestorer_RestoreById_Call{Call: _e.mock.On("RestoreById", ctx, id)}
+}
+
+func (_c *MockPostRestorer_RestoreById_Call) Run(run func(ctx context.Context, id bson.ObjectID)) *MockPostRestorer_RestoreById_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(bson.ObjectID))
+	})
+	return _c
+}
+
+func (_c *MockPostRestorer_RestoreById_Call) Return(post *posts.Post, err error) *MockPostRestorer_RestoreById_Call {
+	_c.Call.Return(post, err)
+	return _c
+}
+
+func (_c *MockPostRestorer_RestoreById_Call) RunAndReturn(run func(ctx context.Context, id bson.ObjectID) (*posts.Post, error)) *MockPostRestorer_RestoreById_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockWebhookDeliveryFinder creates a new instance of MockWebhookDeliveryFinder. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockWebhookDeliveryFinder(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockWebhookDeliveryFinder {
+	mock := &MockWebhookDeliveryFinder{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockWebhookDeliveryFinder is an autogenerated mock type for the WebhookDeliveryFinder type
+type MockWebhookDeliveryFinder struct {
+	mock.Mock
+}
+
+type MockWebhookDeliveryFinder_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockWebhookDeliveryFinder) EXPECT() *MockWebhookDeliveryFinder_Expecter {
+	return &MockWebhookDeliveryFinder_Expecter{mock: &_m.Mock}
+}
+
+// FindDeliveries provides a mock function for the type MockWebhookDeliveryFinder
+func (_mock *MockWebhookDeliveryFinder) FindDeliveries(ctx context.Context, status webhooks.Status, limit int) ([]*webhooks.Delivery, error) {
+	ret := _mock.Called(ctx, status, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindDeliveries")
+	}
+
+	var r0 []*webhooks.Delivery
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, webhooks.Status, int) ([]*webhooks.Delivery, error)); ok {
+		return returnFunc(ctx, status, limit)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, webhooks.Status, int) []*webhooks.Delivery); ok {
+		r0 = returnFunc(ctx, status, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*webhooks.Delivery)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, webhooks.Status, int) error); ok {
+		r1 = returnFunc(ctx, status, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockWebhookDeliveryFinder_FindDeliveries_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindDeliveries'
+type MockWebhookDeliveryFinder_FindDeliveries_Call struct {
+	*mock.Call
+}
+
+// FindDeliveries is a helper method to define mock.On call
+//   - ctx
+//   - status
+//   - limit
+func (_e *MockWebhookDeliveryFinder_Expecter) FindDeliveries(ctx interface{}, status interface{}, limit interface{}) *MockWebhookDeliveryFinder_FindDeliveries_Call {
+	return &MockWebhookDeliveryFinder_FindDeliveries_Call{Call: _e.mock.On("FindDeliveries", ctx, status, limit)}
+}
+
+func (_c *MockWebhookDeliveryFinder_FindDeliveries_Call) Run(run func(ctx context.Context, status webhooks.Status, limit int)) *MockWebhookDeliveryFinder_FindDeliveries_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(webhooks.Status), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *MockWebhookDeliveryFinder_FindDeliveries_Call) Return(deliverys []*webhooks.Delivery, err error) *MockWebhookDeliveryFinder_FindDeliveries_Call {
+	_c.Call.Return(deliverys, err)
+	return _c
+}
+
+func (_c *MockWebhookDeliveryFinder_FindDeliveries_Call) RunAndReturn(run func(ctx context.Context, status webhooks.Status, limit int) ([]*webhooks.Delivery, error)) *MockWebhookDeliveryFinder_FindDeliveries_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockWebhookRedeliverer creates a new instance of MockWebhookRedeliverer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockWebhookRedeliverer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockWebhookRedeliverer {
+	mock := &MockWebhookRedeliverer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockWebhookRedeliverer is an autogenerated mock type for the WebhookRedeliverer type
+type MockWebhookRedeliverer struct {
+	mock.Mock
+}
+
+type MockWebhookRedeliverer_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockWebhookRedeliverer) EXPECT() *MockWebhookRedeliverer_Expecter {
+	return &MockWebhookRedeliverer_Expecter{mock: &_m.Mock}
+}
+
+// Redeliver provides a mock function for the type MockWebhookRedeliverer
+func (_mock *MockWebhookRedeliverer) Redeliver(ctx context.Context, id bson.ObjectID) (*webhooks.Delivery, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Redeliver")
+	}
+
+	var r0 *webhooks.Delivery
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, bson.ObjectID) (*webhooks.Delivery, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, bson.ObjectID) *webhooks.Delivery); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*webhooks.Delivery)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, bson.ObjectID) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockWebhookRedeliverer_Redeliver_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Redeliver'
+type MockWebhookRedeliverer_Redeliver_Call struct {
+	*mock.Call
+}
+
+// Redeliver is a helper method to define mock.On call
+//   - ctx
+//   - id
+func (_e *MockWebhookRedeliverer_Expecter) Redeliver(ctx interface{}, id interface{}) *MockWebhookRedeliverer_Redeliver_Call {
+	return &MockWebhookRedeliverer_Redeliver_Call{Call: _e.mock.On("Redeliver", ctx, id)}
+}
+
+func (_c *MockWebhookRedeliverer_Redeliver_Call) Run(run func(ctx context.Context, id bson.ObjectID)) *MockWebhookRedeliverer_Redeliver_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(bson.ObjectID))
+	})
+	return _c
+}
+
+func (_c *MockWebhookRedeliverer_Redeliver_Call) Return(delivery *webhooks.Delivery, err error) *MockWebhookRedeliverer_Redeliver_Call {
+	_c.Call.Return(delivery, err)
+	return _c
+}
+
+func (_c *MockWebhookRedeliverer_Redeliver_Call) RunAndReturn(run func(ctx context.Context, id bson.ObjectID) (*webhooks.Delivery, error)) *MockWebhookRedeliverer_Redeliver_Call {
+	_c.Call.Return(run)
+	return _c
+}
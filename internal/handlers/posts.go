@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/v2/bson"
 
+	"github.com/mineroot/news/internal/flash"
+	"github.com/mineroot/news/internal/metrics"
 	"github.com/mineroot/news/internal/posts"
 	"github.com/mineroot/news/internal/route"
 	"github.com/mineroot/news/templates"
@@ -20,6 +24,9 @@ func ViewPostHandler(repo PostFinder) echo.HandlerFunc {
 		if err != nil {
 			return err
 		}
+		if notModified(c, pageETag(c, []*posts.Post{post}), post.Updated) {
+			return c.NoContent(http.StatusNotModified)
+		}
 
 		return render(c, http.StatusOK, templates.Post(c.Echo().Reverse, post), post.Title)
 	}
@@ -33,8 +40,16 @@ func ViewCreatePostFormHandler() echo.HandlerFunc {
 }
 
 type postRequest struct {
-	Title   string `form:"title" validate:"required,max=100"`
-	Content string `form:"content" validate:"required,max=50000"`
+	Title   string `form:"title" validate:"required,post_title"`
+	Content string `form:"content" validate:"required,post_content"`
+}
+
+// ValidatePost checks title and content with the same rules as post form, e.g. for imported posts
+func ValidatePost(validate *validator.Validate, title, content string) error {
+	if err := validate.Struct(postRequest{Title: title, Content: content}); err != nil {
+		return errors.New(strings.Join(fieldErrors(err), ", "))
+	}
+	return nil
 }
 
 func CreatePostHandler(repo PostCreator, validate *validator.Validate) echo.HandlerFunc {
@@ -61,8 +76,9 @@ func CreatePostHandler(repo PostCreator, validate *validator.Validate) echo.Hand
 			return err
 		}
 
-		c.Response().Header().Set("HX-Push-Url", c.Echo().Reverse(route.ViewPost, post.ID.Hex()))
-		return render(c, http.StatusOK, templates.Post(c.Echo().Reverse, post), post.Title)
+		metrics.PostsCreated.Inc()
+		flash.Set(c, flash.Success("Post saved"))
+		return c.Redirect(http.StatusSeeOther, c.Echo().Reverse(route.ViewPost, post.ID.Hex()))
 	}
 }
 
@@ -82,7 +98,7 @@ func UpdatePostHandler(repo PostUpdater, validate *validator.Validate) echo.Hand
 	return func(c echo.Context) error {
 		oid, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			return echo.NewHTTPError(http.StatusNotFound)
+			return posts.ErrNotFound
 		}
 		req, err := bindAndValidateRequest[postRequest](c, validate)
 		if err != nil {
@@ -98,12 +114,10 @@ func UpdatePostHandler(repo PostUpdater, validate *validator.Validate) echo.Hand
 		if err != nil {
 			return err
 		}
-		if post == nil {
-			return echo.NewHTTPError(http.StatusNotFound)
-		}
 
-		c.Response().Header().Set("HX-Push-Url", c.Echo().Reverse(route.ViewPost, post.ID.Hex()))
-		return render(c, http.StatusOK, templates.Post(c.Echo().Reverse, post), post.Title)
+		metrics.PostsUpdated.Inc()
+		flash.Set(c, flash.Success("Post saved"))
+		return c.Redirect(http.StatusSeeOther, c.Echo().Reverse(route.ViewPost, post.ID.Hex()))
 	}
 }
 
@@ -111,32 +125,41 @@ func DeletePostHandler(repo PostDeleter) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		oid, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			return echo.NewHTTPError(http.StatusNotFound)
+			return posts.ErrNotFound
 		}
 		post, err := repo.DeleteById(c.Request().Context(), oid)
 		if err != nil {
 			return err
 		}
-		if post == nil {
-			return echo.NewHTTPError(http.StatusNotFound)
-		}
 
+		metrics.PostsDeleted.Inc()
+		undoUrl := c.Echo().Reverse(route.RestorePost, post.ID.Hex())
+		flash.Set(c, flash.Success("Post deleted").WithAction("Undo", undoUrl))
 		return c.Redirect(http.StatusSeeOther, c.Echo().Reverse(route.ViewHome))
 	}
 }
 
+func RestorePostHandler(repo PostRestorer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		oid, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			return posts.ErrNotFound
+		}
+		post, err := repo.RestoreById(c.Request().Context(), oid)
+		if err != nil {
+			return err
+		}
+
+		flash.Set(c, flash.Success("Post restored"))
+		return c.Redirect(http.StatusSeeOther, c.Echo().Reverse(route.ViewPost, post.ID.Hex()))
+	}
+}
+
 func findPostFromId(ctx context.Context, repo PostFinder, id string) (*posts.Post, error) {
 	oid, err := bson.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, echo.NewHTTPError(http.StatusNotFound)
-	}
-	post, err := repo.FindById(ctx, oid)
-	if err != nil {
-		return nil, err
-	}
-	if post == nil {
-		return nil, echo.NewHTTPError(http.StatusNotFound)
+		return nil, posts.ErrNotFound
 	}
 
-	return post, nil
+	return repo.FindById(ctx, oid)
 }
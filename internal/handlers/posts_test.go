@@ -7,14 +7,16 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/a-h/templ"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/v2/bson"
 
+	"github.com/mineroot/news/internal/flash"
 	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/middlewares"
 	"github.com/mineroot/news/internal/posts"
 	"github.com/mineroot/news/internal/route"
 )
@@ -25,7 +27,7 @@ func TestViewPostHandler(t *testing.T) {
 
 	m := NewMockPostFinder(t)
 	m.EXPECT().FindById(mock.Anything, oid).Return(&posts.Post{ID: oid}, nil)
-	m.EXPECT().FindById(mock.Anything, mock.Anything).Return(nil, nil)
+	m.EXPECT().FindById(mock.Anything, mock.Anything).Return(nil, posts.ErrNotFound)
 
 	// success
 	rec := httptest.NewRecorder()
@@ -41,9 +43,7 @@ func TestViewPostHandler(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues("invalid_id")
 	err := handlers.ViewPostHandler(m)(c)
-	var httpErr *echo.HTTPError
-	require.ErrorAs(t, err, &httpErr)
-	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
 }
 
 func TestCreatePostHandler(t *testing.T) {
@@ -53,7 +53,7 @@ func TestCreatePostHandler(t *testing.T) {
 	createdOid := bson.NewObjectID()
 	m := NewMockPostCreator(t)
 	m.EXPECT().Create(mock.Anything, mock.Anything).Return(&posts.Post{ID: createdOid}, nil)
-	validation := validator.New(validator.WithRequiredStructEnabled())
+	validation := handlers.NewValidator(100, 50000)
 
 	// success
 	rec := httptest.NewRecorder()
@@ -64,8 +64,9 @@ func TestCreatePostHandler(t *testing.T) {
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
 	c := e.NewContext(req, rec)
 	require.NoError(t, handlers.CreatePostHandler(m, validation)(c))
-	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Equal(t, "/posts/"+createdOid.Hex(), rec.Header().Get("HX-Push-Url"))
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/posts/"+createdOid.Hex(), rec.Header().Get("Location"))
+	assertFlashCookie(t, rec)
 
 	// validation errors
 	rec = httptest.NewRecorder()
@@ -77,7 +78,7 @@ func TestCreatePostHandler(t *testing.T) {
 	c = e.NewContext(req, rec)
 	require.NoError(t, handlers.CreatePostHandler(m, validation)(c))
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Equal(t, "", rec.Header().Get("HX-Push-Url")) // assert empty header value
+	assert.Equal(t, "", rec.Header().Get("Location")) // assert empty header value
 }
 
 func TestViewUpdatePostFormHandler(t *testing.T) {
@@ -86,7 +87,7 @@ func TestViewUpdatePostFormHandler(t *testing.T) {
 
 	m := NewMockPostFinder(t)
 	m.EXPECT().FindById(mock.Anything, oid).Return(&posts.Post{ID: oid}, nil)
-	m.EXPECT().FindById(mock.Anything, mock.Anything).Return(nil, nil)
+	m.EXPECT().FindById(mock.Anything, mock.Anything).Return(nil, posts.ErrNotFound)
 
 	// success
 	rec := httptest.NewRecorder()
@@ -102,9 +103,7 @@ func TestViewUpdatePostFormHandler(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues("invalid_id")
 	err := handlers.ViewUpdatePostFormHandler(m)(c)
-	var httpErr *echo.HTTPError
-	require.ErrorAs(t, err, &httpErr)
-	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
 }
 
 func TestUpdatePostHandler(t *testing.T) {
@@ -114,8 +113,8 @@ func TestUpdatePostHandler(t *testing.T) {
 	oidToUpdate := bson.NewObjectID()
 	m := NewMockPostUpdater(t)
 	m.EXPECT().UpdateById(mock.Anything, oidToUpdate, mock.Anything, mock.Anything).Return(&posts.Post{ID: oidToUpdate}, nil)
-	m.EXPECT().UpdateById(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
-	validation := validator.New(validator.WithRequiredStructEnabled())
+	m.EXPECT().UpdateById(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, posts.ErrNotFound)
+	validation := handlers.NewValidator(100, 50000)
 
 	// success
 	rec := httptest.NewRecorder()
@@ -128,8 +127,9 @@ func TestUpdatePostHandler(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(oidToUpdate.Hex())
 	require.NoError(t, handlers.UpdatePostHandler(m, validation)(c))
-	assert.Equal(t, "/posts/"+oidToUpdate.Hex(), rec.Header().Get("HX-Push-Url"))
-	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/posts/"+oidToUpdate.Hex(), rec.Header().Get("Location"))
+	assertFlashCookie(t, rec)
 
 	// validation errors
 	rec = httptest.NewRecorder()
@@ -143,7 +143,7 @@ func TestUpdatePostHandler(t *testing.T) {
 	c.SetParamValues(oidToUpdate.Hex())
 	require.NoError(t, handlers.UpdatePostHandler(m, validation)(c))
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Equal(t, "", rec.Header().Get("HX-Push-Url")) // assert empty header value
+	assert.Equal(t, "", rec.Header().Get("Location")) // assert empty header value
 
 	// not found
 	oidNotFound := bson.NewObjectID()
@@ -157,19 +157,18 @@ func TestUpdatePostHandler(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(oidNotFound.Hex())
 	err := handlers.UpdatePostHandler(m, validation)(c)
-	var httpErr *echo.HTTPError
-	require.ErrorAs(t, err, &httpErr)
-	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
 }
 
 func TestDeletePostHandler(t *testing.T) {
 	e := echo.New()
 	e.GET(route.ViewHome, nil).Name = route.ViewHome
+	e.POST(route.RestorePost, nil).Name = route.RestorePost
 
 	oidToDelete := bson.NewObjectID()
 	m := NewMockPostDeleter(t)
 	m.EXPECT().DeleteById(mock.Anything, oidToDelete).Return(&posts.Post{ID: oidToDelete}, nil)
-	m.EXPECT().DeleteById(mock.Anything, mock.Anything).Return(nil, nil)
+	m.EXPECT().DeleteById(mock.Anything, mock.Anything).Return(nil, posts.ErrNotFound)
 
 	// success
 	rec := httptest.NewRecorder()
@@ -179,6 +178,7 @@ func TestDeletePostHandler(t *testing.T) {
 	require.NoError(t, handlers.DeletePostHandler(m)(c))
 	assert.Equal(t, http.StatusSeeOther, rec.Code)
 	assert.Equal(t, "/", rec.Header().Get("Location"))
+	assertFlashCookie(t, rec)
 
 	// not found
 	oidNotFound := bson.NewObjectID()
@@ -187,7 +187,162 @@ func TestDeletePostHandler(t *testing.T) {
 	c.SetParamNames("id")
 	c.SetParamValues(oidNotFound.Hex())
 	err := handlers.DeletePostHandler(m)(c)
-	var httpErr *echo.HTTPError
-	require.ErrorAs(t, err, &httpErr)
-	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+}
+
+func TestRestorePostHandler(t *testing.T) {
+	e := echo.New()
+	e.GET(route.ViewPost, nil).Name = route.ViewPost
+
+	oidToRestore := bson.NewObjectID()
+	m := NewMockPostRestorer(t)
+	m.EXPECT().RestoreById(mock.Anything, oidToRestore).Return(&posts.Post{ID: oidToRestore}, nil)
+	m.EXPECT().RestoreById(mock.Anything, mock.Anything).Return(nil, posts.ErrNotFound)
+
+	// success
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/posts/"+oidToRestore.Hex()+"/restore", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(oidToRestore.Hex())
+	require.NoError(t, handlers.RestorePostHandler(m)(c))
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/posts/"+oidToRestore.Hex(), rec.Header().Get("Location"))
+	assertFlashCookie(t, rec)
+
+	// not found (e.g. trash was purged)
+	oidNotFound := bson.NewObjectID()
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodPost, "/posts/"+oidNotFound.Hex()+"/restore", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(oidNotFound.Hex())
+	err := handlers.RestorePostHandler(m)(c)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+}
+
+func TestRender_ShowsFlash(t *testing.T) {
+	e := echo.New()
+	oid := bson.NewObjectID()
+
+	m := NewMockPostFinder(t)
+	m.EXPECT().FindById(mock.Anything, oid).Return(&posts.Post{ID: oid}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/"+oid.Hex(), nil)
+	prev := httptest.NewRecorder()
+	flash.Set(e.NewContext(req, prev), flash.Success("Post saved"))
+	req.AddCookie(prev.Result().Cookies()[0])
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(oid.Hex())
+	require.NoError(t, handlers.ViewPostHandler(m)(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Post saved")
+}
+
+func TestRender_HtmxPartial(t *testing.T) {
+	e := echo.New()
+	oid := bson.NewObjectID()
+	m := NewMockPostFinder(t)
+	m.EXPECT().FindById(mock.Anything, oid).Return(&posts.Post{ID: oid, Title: "Post Title", Content: "Post Content"}, nil)
+
+	testCases := []struct {
+		name    string
+		headers map[string]string
+		partial bool
+	}{
+		{"full page", nil, false},
+		{"htmx request", map[string]string{"HX-Request": "true"}, true},
+		{"history restore", map[string]string{"HX-Request": "true", "HX-History-Restore-Request": "true"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts/"+oid.Hex()+"?q=query", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(oid.Hex())
+			require.NoError(t, handlers.ViewPostHandler(m)(c))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			body := rec.Body.String()
+			assert.Contains(t, body, `<div id="main"`)
+			assert.Contains(t, body, "Post Content")
+			assert.Contains(t, body, "<title>Post Title</title>")
+			assert.Contains(t, body, `value="query"`)
+			if tc.partial {
+				assert.NotContains(t, body, "<html")
+				assert.NotContains(t, body, "<header")
+				assert.Contains(t, body, `id="search" hx-swap-oob="true"`)
+			} else {
+				assert.Contains(t, body, "<html")
+				assert.Contains(t, body, "<header")
+				assert.NotContains(t, body, "hx-swap-oob")
+			}
+		})
+	}
+}
+
+func TestRender_ScriptNonce(t *testing.T) {
+	e := echo.New()
+	oid := bson.NewObjectID()
+
+	m := NewMockPostFinder(t)
+	m.EXPECT().FindById(mock.Anything, oid).Return(&posts.Post{ID: oid}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/"+oid.Hex(), nil)
+	req = req.WithContext(templ.WithNonce(req.Context(), "test-nonce"))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(oid.Hex())
+	require.NoError(t, handlers.ViewPostHandler(m)(c))
+	assert.Contains(t, rec.Body.String(), `nonce="test-nonce"`)
+}
+
+func TestRender_CSRFToken(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/posts/new", nil), rec)
+	c.Set(middlewares.CSRFContextKey, "test-token")
+	require.NoError(t, handlers.ViewCreatePostFormHandler()(c))
+	body := rec.Body.String()
+	assert.Contains(t, body, `hx-headers="{&#34;X-CSRF-Token&#34;:&#34;test-token&#34;}"`)
+	assert.Contains(t, body, `name="_csrf" value="test-token"`)
+}
+
+func assertFlashCookie(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "flash" && cookie.Value != "" {
+			return
+		}
+	}
+	assert.Fail(t, "flash cookie is not set")
+}
+
+func TestNewValidator(t *testing.T) {
+	e := echo.New()
+	e.GET(route.ViewPost, nil).Name = route.ViewPost
+	m := NewMockPostCreator(t)
+
+	f := make(url.Values)
+	f.Set("title", "Too long title")
+	f.Set("content", "Some content")
+	req := httptest.NewRequest(http.MethodPost, "/posts/new", strings.NewReader(f.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	require.NoError(t, handlers.CreatePostHandler(m, handlers.NewValidator(5, 50000))(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid &#39;Title&#39; value: max 5")
+}
+
+func TestValidatePost(t *testing.T) {
+	validate := handlers.NewValidator(5, 50000)
+	assert.NoError(t, handlers.ValidatePost(validate, "Title", "Content"))
+	assert.EqualError(t, handlers.ValidatePost(validate, "Too long", ""), "invalid 'Title' value: max 5, invalid 'Content' value: required ")
 }
@@ -6,23 +6,26 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"github.com/mineroot/news/internal/metrics"
 	"github.com/mineroot/news/internal/paging"
 	"github.com/mineroot/news/internal/route"
 	"github.com/mineroot/news/templates"
 )
 
-func ViewSearchHandler(repo PostsPaginator) echo.HandlerFunc {
+// ViewSearchHandler rejects queries shorter than minQueryLength characters, as text index ignores most of them anyway
+func ViewSearchHandler(repo PostsPaginator, pageSize, minQueryLength int) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		q := strings.TrimSpace(c.QueryParam("q"))
 		rq := []rune(q)
-		if len(rq) < 3 {
-			return render(c, http.StatusOK, templates.Error("Search query must be at least 3 characters"), "Home")
+		if len(rq) < minQueryLength {
+			msg := fmt.Sprintf("Search query must be at least %d characters", minQueryLength)
+			return render(c, http.StatusOK, templates.Error(msg), "Home")
 		}
 
-		const pageSize = 4
 		paginator, err := paging.NewPaginator(c.QueryParam("page"), pageSize)
 		if err != nil {
 			params := make(url.Values)
@@ -36,7 +39,11 @@ func ViewSearchHandler(repo PostsPaginator) echo.HandlerFunc {
 			return err
 		}
 
-		paginator.SetRealItemsCount(total)
+		if total.Items == 0 {
+			metrics.SearchesWithoutResults.Inc()
+		}
+
+		paginator.SetTotal(total)
 		if paginator.NeedsRedirect() {
 			params := make(url.Values)
 			params.Set("q", c.QueryParam("q"))
@@ -44,6 +51,10 @@ func ViewSearchHandler(repo PostsPaginator) echo.HandlerFunc {
 			loc := fmt.Sprintf("%s?%s", c.Echo().Reverse(route.ViewSearch), params.Encode())
 			return c.Redirect(http.StatusTemporaryRedirect, loc)
 		}
+		// no Last-Modified, as on home page
+		if notModified(c, pageETag(c, all, total), time.Time{}) {
+			return c.NoContent(http.StatusNotModified)
+		}
 
 		return render(c, http.StatusOK, templates.Home(c.Echo().Reverse, all, paginator, c.QueryParam("q")), "Home")
 	}
@@ -3,36 +3,27 @@ package handlers
 import (
 	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/a-h/templ"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
-	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
 
+	"github.com/mineroot/news/internal/flash"
+	"github.com/mineroot/news/internal/middlewares"
+	"github.com/mineroot/news/internal/tracing"
 	"github.com/mineroot/news/templates"
 )
 
-func ErrorHandler(logger *zerolog.Logger) func(err error, c echo.Context) {
-	return func(err error, c echo.Context) {
-		code := http.StatusInternalServerError
-		var he *echo.HTTPError
-		if errors.As(err, &he) {
-			code = he.Code
-		}
+var tracer = otel.Tracer("github.com/mineroot/news/internal/handlers")
 
-		switch code {
-		case http.StatusNotFound:
-			_ = render(c, http.StatusNotFound, templates.Error("Page not found"), "Page not found")
-		default:
-			_ = render(c, http.StatusInternalServerError, templates.Error("Internal server error"), "Internal server error")
-			logger.Error().
-				Str("uri", c.Request().RequestURI).
-				Err(err).
-				Msg("server error")
-		}
-	}
+// NewValidator creates validator with post_title and post_content aliases used by post requests
+func NewValidator(titleMaxLength, contentMaxLength int) *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterAlias("post_title", fmt.Sprintf("max=%d", titleMaxLength))
+	validate.RegisterAlias("post_content", fmt.Sprintf("max=%d", contentMaxLength))
+	return validate
 }
 
 func bindAndValidateRequest[T any](c echo.Context, validate *validator.Validate) (*T, error) {
@@ -41,14 +32,10 @@ func bindAndValidateRequest[T any](c echo.Context, validate *validator.Validate)
 		return nil, errors.New("invalid input")
 	}
 	if err := validate.Struct(req); err != nil {
-		var errs validator.ValidationErrors
-		if !errors.As(err, &errs) {
-			panic("must be unreachable")
-		}
 		b := strings.Builder{}
 		b.WriteString("Validation errors:\n")
-		for _, fieldErr := range errs {
-			b.WriteString(fmt.Sprintf("invalid '%s' value: %s %s\n", fieldErr.Field(), fieldErr.Tag(), fieldErr.Param()))
+		for _, msg := range fieldErrors(err) {
+			b.WriteString(msg + "\n")
 		}
 		return nil, errors.New(b.String())
 	}
@@ -56,15 +43,51 @@ func bindAndValidateRequest[T any](c echo.Context, validate *validator.Validate)
 	return &req, nil
 }
 
+// fieldErrors describes every invalid field of validator error
+func fieldErrors(err error) []string {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		panic("must be unreachable")
+	}
+	messages := make([]string, 0, len(errs))
+	for _, fieldErr := range errs {
+		messages = append(messages, fmt.Sprintf("invalid '%s' value: %s %s", fieldErr.Field(), fieldErr.ActualTag(), fieldErr.Param()))
+	}
+	return messages
+}
+
+// isPartial reports whether htmx swaps the main content only, so layout is not needed.
+// History restore after cache miss replaces the whole body, it needs full page
+func isPartial(c echo.Context) bool {
+	h := c.Request().Header
+	return h.Get("HX-Request") == "true" && h.Get("HX-History-Restore-Request") != "true"
+}
+
 func render(c echo.Context, statusCode int, t templ.Component, title string) error {
+	search, message := c.QueryParam("q"), flash.Pop(c)
+	page := templates.Layout(c.Echo().Reverse, title, search, message)
+	if isPartial(c) {
+		page = templates.Partial(title, search, message)
+	}
+	return renderPage(c, statusCode, page, t)
+}
+
+// renderAdmin renders t in layout of admin server pages
+func renderAdmin(c echo.Context, statusCode int, t templ.Component, title string) error {
+	return renderPage(c, statusCode, templates.AdminLayout(c.Echo().Reverse, title, flash.Pop(c)), t)
+}
+
+func renderPage(c echo.Context, statusCode int, page, t templ.Component) error {
+	ctx, span := tracer.Start(c.Request().Context(), "templ.render")
 	buf := templ.GetBuffer()
 	defer templ.ReleaseBuffer(buf)
 
-	search := c.QueryParam("q")
-	if err := templates.Layout(c.Echo().Reverse, title, search).Render(
-		templ.WithChildren(c.Request().Context(), t),
-		buf,
-	); err != nil {
+	if token, ok := c.Get(middlewares.CSRFContextKey).(string); ok {
+		ctx = templates.WithCSRFToken(ctx, token)
+	}
+	err := page.Render(templ.WithChildren(ctx, t), buf)
+	tracing.End(span, err)
+	if err != nil {
 		return err
 	}
 
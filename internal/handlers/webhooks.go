@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/flash"
+	"github.com/mineroot/news/internal/route"
+	"github.com/mineroot/news/internal/webhooks"
+	"github.com/mineroot/news/templates"
+)
+
+// ViewWebhookDeliveriesHandler shows the latest limit deliveries on admin server, "status" query param filters them
+func ViewWebhookDeliveriesHandler(repo WebhookDeliveryFinder, limit int) echo.HandlerFunc {
+	statuses := []webhooks.Status{"", webhooks.StatusPending, webhooks.StatusDelivered, webhooks.StatusDead}
+	return func(c echo.Context) error {
+		status := webhooks.Status(c.QueryParam("status"))
+		if !slices.Contains(statuses, status) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown delivery status.")
+		}
+		deliveries, err := repo.FindDeliveries(c.Request().Context(), status, limit)
+		if err != nil {
+			return err
+		}
+
+		return renderAdmin(c, http.StatusOK, templates.WebhookDeliveries(c.Echo().Reverse, deliveries, status), "Webhook deliveries")
+	}
+}
+
+// RedeliverWebhookHandler queues dead delivery again
+func RedeliverWebhookHandler(repo WebhookRedeliverer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		oid, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			return webhooks.ErrNotFound
+		}
+		if _, err := repo.Redeliver(c.Request().Context(), oid); err != nil {
+			return err
+		}
+
+		flash.Set(c, flash.Success("Delivery is queued again"))
+		return c.Redirect(http.StatusSeeOther, c.Echo().Reverse(route.ViewWebhookDeliveries)+"?status="+string(webhooks.StatusDead))
+	}
+}
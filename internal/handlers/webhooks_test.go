@@ -0,0 +1,95 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/handlers"
+	"github.com/mineroot/news/internal/route"
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+func TestViewWebhookDeliveriesHandler(t *testing.T) {
+	e := echo.New()
+	e.GET(route.ViewWebhookDeliveries, nil).Name = route.ViewWebhookDeliveries
+	e.POST(route.RedeliverWebhook, nil).Name = route.RedeliverWebhook
+
+	dead := &webhooks.Delivery{
+		ID:         bson.NewObjectID(),
+		URL:        "https://example.com/hook?token=secret",
+		Event:      webhooks.PostDeleted,
+		Status:     webhooks.StatusDead,
+		Attempts:   10,
+		LastStatus: http.StatusInternalServerError,
+		LastError:  "unexpected status 500 Internal Server Error",
+		Created:    time.Now(),
+	}
+	m := NewMockWebhookDeliveryFinder(t)
+	m.EXPECT().FindDeliveries(mock.Anything, webhooks.StatusDead, 50).Return([]*webhooks.Delivery{dead}, nil)
+	m.EXPECT().FindDeliveries(mock.Anything, webhooks.Status(""), 50).Return([]*webhooks.Delivery{}, nil)
+
+	// dead letters
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/webhooks/deliveries?status=dead", nil), rec)
+	require.NoError(t, handlers.ViewWebhookDeliveriesHandler(m, 50)(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "post.deleted &rarr; example.com/hook")
+	assert.NotContains(t, body, "token=secret")
+	assert.Contains(t, body, "unexpected status 500 Internal Server Error")
+	assert.Contains(t, body, `<form method="post" action="/webhooks/deliveries/`+dead.ID.Hex()+`/redeliver">`)
+	// admin server serves neither htmx nor public pages
+	assert.NotContains(t, body, "hx-")
+	assert.NotContains(t, body, "Search...")
+
+	// all
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/webhooks/deliveries", nil), rec)
+	require.NoError(t, handlers.ViewWebhookDeliveriesHandler(m, 50)(c))
+	assert.Contains(t, rec.Body.String(), "No deliveries")
+
+	// unknown status
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/webhooks/deliveries?status=lost", nil), rec)
+	err := handlers.ViewWebhookDeliveriesHandler(m, 50)(c)
+	var he *echo.HTTPError
+	require.ErrorAs(t, err, &he)
+	assert.Equal(t, http.StatusBadRequest, he.Code)
+}
+
+func TestRedeliverWebhookHandler(t *testing.T) {
+	e := echo.New()
+	e.GET(route.ViewWebhookDeliveries, nil).Name = route.ViewWebhookDeliveries
+
+	oid := bson.NewObjectID()
+	m := NewMockWebhookRedeliverer(t)
+	m.EXPECT().Redeliver(mock.Anything, oid).Return(&webhooks.Delivery{ID: oid, Status: webhooks.StatusPending}, nil)
+	m.EXPECT().Redeliver(mock.Anything, mock.Anything).Return(nil, webhooks.ErrNotFound)
+
+	// success
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/webhooks/deliveries/"+oid.Hex()+"/redeliver", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(oid.Hex())
+	require.NoError(t, handlers.RedeliverWebhookHandler(m)(c))
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/webhooks/deliveries?status=dead", rec.Header().Get("Location"))
+	assertFlashCookie(t, rec)
+
+	// not dead or not found
+	oidNotFound := bson.NewObjectID()
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodPost, "/webhooks/deliveries/"+oidNotFound.Hex()+"/redeliver", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(oidNotFound.Hex())
+	err := handlers.RedeliverWebhookHandler(m)(c)
+	assert.ErrorIs(t, err, webhooks.ErrNotFound)
+}
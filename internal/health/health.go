@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	LivezPath  = "/livez"
+	ReadyzPath = "/readyz"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Check is a single readiness dependency check, it should respect ctx deadline
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+type Checker struct {
+	checks   []Check
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// SetDraining makes the instance not ready, so load balancer stops sending new requests during shutdown
+func (h *Checker) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Run executes all checks concurrently
+func (h *Checker) Run(ctx context.Context) Report {
+	if h.draining.Load() {
+		return Report{Status: StatusDraining}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make([]CheckResult, len(h.checks))}
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Check(ctx)
+			result := CheckResult{
+				Name:    check.Name,
+				Status:  StatusOK,
+				Latency: time.Since(start).String(),
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+			report.Checks[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// LivezHandler reports that the process is up and able to serve http requests, it never checks dependencies
+func (h *Checker) LivezHandler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return respond(c, http.StatusOK, Report{Status: StatusOK}, false)
+	}
+}
+
+// ReadyzHandler reports whether the instance is able to serve traffic, it writes plain status only
+func (h *Checker) ReadyzHandler() echo.HandlerFunc {
+	return h.readyz(false)
+}
+
+// VerboseReadyzHandler is ReadyzHandler writing per check details in JSON when ?verbose is set.
+// Check errors may reveal hosts and internals, so it's served by admin server only
+func (h *Checker) VerboseReadyzHandler() echo.HandlerFunc {
+	return h.readyz(true)
+}
+
+func (h *Checker) readyz(allowVerbose bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		report := h.Run(c.Request().Context())
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		_, verbose := c.QueryParams()["verbose"]
+		return respond(c, code, report, allowVerbose && verbose)
+	}
+}
+
+// respond writes plain status, or per check details in JSON when verbose
+func respond(c echo.Context, code int, report Report, verbose bool) error {
+	if c.Request().Method == http.MethodHead {
+		return c.NoContent(code)
+	}
+	if verbose {
+		return c.JSON(code, report)
+	}
+	return c.String(code, report.Status)
+}
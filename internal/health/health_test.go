@@ -0,0 +1,83 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/health"
+)
+
+func TestReadyzHandler(t *testing.T) {
+	e := echo.New()
+	var mongoErr error
+	checker := health.NewChecker(time.Second,
+		health.Check{Name: "mongo", Check: func(ctx context.Context) error { return mongoErr }},
+		health.Check{Name: "text_index", Check: func(ctx context.Context) error { return nil }},
+	)
+
+	// ready
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), rec)
+	require.NoError(t, checker.ReadyzHandler()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+
+	// mongo is down, public probe hides details
+	mongoErr = errors.New("connection refused")
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz?verbose", nil), rec)
+	require.NoError(t, checker.ReadyzHandler()(c))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "fail", rec.Body.String())
+
+	// verbose mode of admin server
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz?verbose", nil), rec)
+	require.NoError(t, checker.VerboseReadyzHandler()(c))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var report health.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusFail, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, "mongo", report.Checks[0].Name)
+	assert.Equal(t, health.StatusFail, report.Checks[0].Status)
+	assert.Equal(t, "connection refused", report.Checks[0].Error)
+	assert.NotEmpty(t, report.Checks[0].Latency)
+	assert.Equal(t, health.StatusOK, report.Checks[1].Status)
+
+	// draining
+	mongoErr = nil
+	checker.SetDraining()
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), rec)
+	require.NoError(t, checker.ReadyzHandler()(c))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "draining", rec.Body.String())
+
+	// liveness does not depend on readiness
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodHead, "/livez", nil), rec)
+	require.NoError(t, checker.LivezHandler()(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRun_Timeout(t *testing.T) {
+	checker := health.NewChecker(10*time.Millisecond,
+		health.Check{Name: "slow", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	report := checker.Run(context.Background())
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
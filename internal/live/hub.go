@@ -0,0 +1,113 @@
+package live
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mineroot/news/internal/metrics"
+	"github.com/mineroot/news/internal/posts"
+)
+
+type Kind string
+
+const (
+	Created Kind = "created"
+	Updated Kind = "updated"
+	Deleted Kind = "deleted"
+)
+
+// Event is a change of a post, Post has only ID when it's deleted
+type Event struct {
+	Kind Kind
+	Post *posts.Post
+}
+
+// Source watches posts changes
+type Source interface {
+	// Watch calls fn for every change until ctx is cancelled
+	Watch(ctx context.Context, fn func(Event)) error
+}
+
+// Subscription receives events published after it was made, its channel is closed when subscriber
+// is too slow to read them or hub is closed
+type Subscription struct {
+	events chan Event
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Hub fans out events to subscribers of this replica
+type Hub struct {
+	mu          sync.Mutex
+	buffer      int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub buffers up to buffer events for every subscriber
+func NewHub(buffer int) *Hub {
+	return &Hub{buffer: buffer, subscribers: make(map[*Subscription]struct{})}
+}
+
+func (h *Hub) Subscribe() *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{events: make(chan Event, h.buffer)}
+	if h.closed {
+		close(sub.events)
+		return sub
+	}
+	h.subscribers[sub] = struct{}{}
+	metrics.LiveSubscribers.Inc()
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+}
+
+// Publish never blocks, subscribers with full buffer are dropped, so they reconnect and reload
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+			metrics.LiveDropped.Inc()
+		}
+	}
+}
+
+// Run publishes events of source until ctx is cancelled, then all subscriptions are closed
+func (h *Hub) Run(ctx context.Context, source Source) error {
+	defer h.close()
+	return source.Watch(ctx, h.Publish)
+}
+
+func (h *Hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+	h.closed = true
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	close(sub.events)
+	metrics.LiveSubscribers.Dec()
+}
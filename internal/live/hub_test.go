@@ -0,0 +1,81 @@
+package live_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/live"
+	"github.com/mineroot/news/internal/posts"
+)
+
+type fakeSource struct {
+	started chan func(live.Event)
+}
+
+func (s *fakeSource) Watch(ctx context.Context, fn func(live.Event)) error {
+	s.started <- fn
+	<-ctx.Done()
+	return nil
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := live.NewHub(2)
+	first := hub.Subscribe()
+	second := hub.Subscribe()
+	event := live.Event{Kind: live.Created, Post: &posts.Post{ID: bson.NewObjectID()}}
+
+	hub.Publish(event)
+	assert.Equal(t, event, <-first.Events())
+	assert.Equal(t, event, <-second.Events())
+
+	hub.Unsubscribe(second)
+	_, ok := <-second.Events()
+	assert.False(t, ok)
+	// unsubscribing twice is allowed, handler always unsubscribes
+	hub.Unsubscribe(second)
+
+	hub.Publish(event)
+	assert.Equal(t, event, <-first.Events())
+}
+
+func TestHub_PublishDropsSlowSubscriber(t *testing.T) {
+	hub := live.NewHub(1)
+	slow := hub.Subscribe()
+	fast := hub.Subscribe()
+	event := live.Event{Kind: live.Updated, Post: &posts.Post{ID: bson.NewObjectID()}}
+
+	hub.Publish(event)
+	<-fast.Events()
+	hub.Publish(event)
+
+	assert.Equal(t, event, <-slow.Events())
+	_, ok := <-slow.Events()
+	assert.False(t, ok, "slow subscriber is dropped")
+	assert.Equal(t, event, <-fast.Events())
+}
+
+func TestHub_Run(t *testing.T) {
+	hub := live.NewHub(1)
+	source := &fakeSource{started: make(chan func(live.Event))}
+	sub := hub.Subscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- hub.Run(ctx, source) }()
+
+	publish := <-source.started
+	event := live.Event{Kind: live.Deleted, Post: &posts.Post{ID: bson.NewObjectID()}}
+	publish(event)
+	assert.Equal(t, event, <-sub.Events())
+
+	cancel()
+	require.NoError(t, <-done)
+	_, ok := <-sub.Events()
+	assert.False(t, ok, "subscriptions are closed on exit")
+	_, ok = <-hub.Subscribe().Events()
+	assert.False(t, ok, "closed hub has no subscriptions")
+}
@@ -0,0 +1,223 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+// mongodb error codes
+const (
+	changeStreamsNotSupported = 40573
+	changeStreamHistoryLost   = 286
+)
+
+// pollLimit is max number of changes read by a single poll, the rest is read by the next ones
+const pollLimit = 100
+
+// Watcher is Source of posts changes from mongodb change stream.
+// Standalone servers have no change streams, so posts and trash collections are polled instead
+type Watcher struct {
+	posts *mongo.Collection
+	trash *mongo.Collection
+	// PollInterval is how often collections are polled when change streams are not supported
+	PollInterval time.Duration
+	// RetryDelay is a pause before failed change stream is reopened
+	RetryDelay time.Duration
+}
+
+func NewWatcher(posts, trash *mongo.Collection) *Watcher {
+	return &Watcher{posts: posts, trash: trash, PollInterval: 2 * time.Second, RetryDelay: time.Second}
+}
+
+// Watch returns nil only after ctx is cancelled, mongodb failures are logged and retried,
+// so they don't stop the app
+func (w *Watcher) Watch(ctx context.Context, fn func(Event)) error {
+	logger := zerolog.Ctx(ctx)
+	var resumeToken bson.Raw
+	for {
+		err := w.watchStream(ctx, &resumeToken, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var se mongo.ServerError
+		if errors.As(err, &se) && se.HasErrorCode(changeStreamsNotSupported) {
+			logger.Info().Msg("change streams are not supported, polling posts for live updates")
+			w.poll(ctx, fn)
+			return nil
+		}
+		if errors.As(err, &se) && se.HasErrorCode(changeStreamHistoryLost) {
+			// changes since resume token are lost anyway
+			resumeToken = nil
+		}
+		logger.Warn().Err(err).Msg("posts change stream failed")
+		if !w.sleep(ctx) {
+			return nil
+		}
+	}
+}
+
+// sleep pauses before retry, it reports false when ctx is cancelled
+func (w *Watcher) sleep(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(w.RetryDelay):
+		return true
+	}
+}
+
+// watchStream reads change stream until it fails, resumeToken is the last read change
+func (w *Watcher) watchStream(ctx context.Context, resumeToken *bson.Raw, fn func(Event)) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "operationType", Value: bson.D{
+			{Key: "$in", Value: bson.A{"insert", "update", "replace", "delete"}},
+		}}}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if *resumeToken != nil {
+		opts.SetResumeAfter(*resumeToken)
+	}
+	stream, err := w.posts.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	for stream.Next(ctx) {
+		var change struct {
+			OperationType string      `bson:"operationType"`
+			FullDocument  *posts.Post `bson:"fullDocument"`
+			DocumentKey   struct {
+				ID bson.ObjectID `bson:"_id"`
+			} `bson:"documentKey"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		*resumeToken = stream.ResumeToken()
+
+		switch {
+		case change.OperationType == "delete":
+			fn(Event{Kind: Deleted, Post: &posts.Post{ID: change.DocumentKey.ID}})
+		case change.FullDocument == nil:
+			// updated post is already deleted, the deletion follows
+		case change.OperationType == "insert":
+			fn(Event{Kind: Created, Post: change.FullDocument})
+		default:
+			fn(Event{Kind: Updated, Post: change.FullDocument})
+		}
+	}
+	return stream.Err()
+}
+
+// poll finds posts updated and deleted since the previous poll. Changes are compared by timestamps set by app,
+// so a change made by replica with clock behind may be missed. It returns after ctx is cancelled
+func (w *Watcher) poll(ctx context.Context, fn func(Event)) {
+	logger := zerolog.Ctx(ctx)
+	var updated, deleted time.Time
+	for {
+		var err error
+		updated, err = w.latest(ctx, w.posts, "updatedAt")
+		if err == nil {
+			deleted, err = w.latest(ctx, w.trash, "deletedAt")
+		}
+		if err == nil {
+			break
+		}
+		if ctx.Err() == nil {
+			logger.Warn().Err(err).Msg("unable to start polling posts")
+		}
+		if !w.sleep(ctx) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var err error
+		if updated, err = w.pollPosts(ctx, updated, fn); err != nil && ctx.Err() == nil {
+			logger.Warn().Err(err).Msg("unable to poll posts")
+		}
+		if deleted, err = w.pollTrash(ctx, deleted, fn); err != nil && ctx.Err() == nil {
+			logger.Warn().Err(err).Msg("unable to poll deleted posts")
+		}
+	}
+}
+
+// pollPosts reports posts updated after since, it returns the last update
+func (w *Watcher) pollPosts(ctx context.Context, since time.Time, fn func(Event)) (time.Time, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: 1}}).SetLimit(pollLimit)
+	cursor, err := w.posts.Find(ctx, bson.D{{Key: "updatedAt", Value: bson.D{{Key: "$gt", Value: since}}}}, opts)
+	if err != nil {
+		return since, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post posts.Post
+		if err := cursor.Decode(&post); err != nil {
+			return since, err
+		}
+		kind := Updated
+		if post.Created.Equal(post.Updated) {
+			kind = Created
+		}
+		fn(Event{Kind: kind, Post: &post})
+		since = post.Updated
+	}
+	return since, cursor.Err()
+}
+
+// pollTrash reports posts deleted after since, it returns the last deletion
+func (w *Watcher) pollTrash(ctx context.Context, since time.Time, fn func(Event)) (time.Time, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: 1}}).SetLimit(pollLimit)
+	cursor, err := w.trash.Find(ctx, bson.D{{Key: "deletedAt", Value: bson.D{{Key: "$gt", Value: since}}}}, opts)
+	if err != nil {
+		return since, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var trashed struct {
+			ID      bson.ObjectID `bson:"_id"`
+			Deleted time.Time     `bson:"deletedAt"`
+		}
+		if err := cursor.Decode(&trashed); err != nil {
+			return since, err
+		}
+		fn(Event{Kind: Deleted, Post: &posts.Post{ID: trashed.ID}})
+		since = trashed.Deleted
+	}
+	return since, cursor.Err()
+}
+
+// latest is the latest value of field, so only later changes are polled
+func (w *Watcher) latest(ctx context.Context, collection *mongo.Collection, field string) (time.Time, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: field, Value: -1}}).SetProjection(bson.D{{Key: field, Value: 1}})
+	raw, err := collection.FindOne(ctx, bson.D{}, opts).Raw()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return time.Now(), nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	at, ok := raw.Lookup(field).TimeOK()
+	if !ok {
+		return time.Now(), nil
+	}
+	return at, nil
+}
@@ -0,0 +1,77 @@
+package live_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/dbtest"
+	"github.com/mineroot/news/internal/live"
+	"github.com/mineroot/news/internal/posts"
+)
+
+// TestWatcher_Poll runs standalone mongodb, which has no change streams, so watcher falls back to polling
+func TestWatcher_Poll(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.MigratedMongo(t)
+
+	postsCollection := db.GetPostsCollection(mongoClient, db.ClientConfig{})
+	trashCollection := db.GetTrashCollection(mongoClient, db.ClientConfig{})
+	repo := posts.NewRepository(postsCollection, trashCollection)
+	watcher := live.NewWatcher(postsCollection, trashCollection)
+	watcher.PollInterval = 10 * time.Millisecond
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	events := make(chan live.Event, 10)
+	done := make(chan error)
+	go func() {
+		done <- watcher.Watch(watchCtx, func(event live.Event) { events <- event })
+	}()
+	// let watcher find out there are no change streams and start polling
+	time.Sleep(time.Second)
+
+	now := time.Now()
+	post, err := repo.Create(ctx, &posts.Post{Title: "Title", Content: "Content", Created: now, Updated: now})
+	require.NoError(t, err)
+	event := <-events
+	assert.Equal(t, live.Created, event.Kind)
+	assert.Equal(t, post.ID, event.Post.ID)
+
+	_, err = repo.UpdateById(ctx, post.ID, "New title", "New content")
+	require.NoError(t, err)
+	event = <-events
+	assert.Equal(t, live.Updated, event.Kind)
+	assert.Equal(t, "New title", event.Post.Title)
+
+	_, err = repo.DeleteById(ctx, post.ID)
+	require.NoError(t, err)
+	event = <-events
+	assert.Equal(t, live.Deleted, event.Kind)
+	assert.Equal(t, post.ID, event.Post.ID)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+// TestWatcher_Unavailable checks that unreachable mongodb is retried until ctx is cancelled, so it doesn't stop the app
+func TestWatcher_Unavailable(t *testing.T) {
+	ctx := context.Background()
+	opts, err := db.ClientOptions(db.ClientConfig{URI: "mongodb://127.0.0.1:1", ServerSelectionTimeout: 10 * time.Millisecond})
+	require.NoError(t, err)
+	// connect doesn't wait for server
+	mongoClient, err := mongo.Connect(opts)
+	require.NoError(t, err)
+	defer func() { _ = mongoClient.Disconnect(ctx) }()
+	watcher := live.NewWatcher(db.GetPostsCollection(mongoClient, db.ClientConfig{}), db.GetTrashCollection(mongoClient, db.ClientConfig{}))
+	watcher.RetryDelay = 10 * time.Millisecond
+
+	watchCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	require.NoError(t, watcher.Watch(watchCtx, func(live.Event) {}))
+	assert.Error(t, watchCtx.Err(), "watch must not return before ctx is cancelled")
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+const namespace = "news"
+
+var (
+	HttpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by echo route name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	MongoCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "command_duration_seconds",
+		Help:      "Duration of MongoDB commands.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "status"})
+
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "Number of created posts.",
+	})
+
+	PostsUpdated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_updated_total",
+		Help:      "Number of updated posts.",
+	})
+
+	PostsDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_deleted_total",
+		Help:      "Number of deleted posts.",
+	})
+
+	SearchesWithoutResults = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "searches_zero_results_total",
+		Help:      "Number of searches which found nothing.",
+	})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests rejected by rate limiter.",
+	}, []string{"limit"})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "posts_cache",
+		Name:      "requests_total",
+		Help:      "Number of posts cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	LiveSubscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "live",
+		Name:      "subscribers",
+		Help:      "Number of connected live updates clients.",
+	})
+
+	LiveDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "live",
+		Name:      "dropped_subscribers_total",
+		Help:      "Number of live updates clients disconnected as too slow.",
+	})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Number of webhook delivery attempts by event and result (delivered, retry or dead).",
+	}, []string{"event", "result"})
+)
+
+// Registry holds all app collectors, it is used instead of the global prometheus registry
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequestDuration,
+		MongoCommandDuration,
+		PostsCreated,
+		PostsUpdated,
+		PostsDeleted,
+		SearchesWithoutResults,
+		RateLimited,
+		CacheRequests,
+		LiveSubscribers,
+		LiveDropped,
+		WebhookDeliveries,
+	)
+}
+
+// Handler exposes metrics in prometheus format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware observes duration of every request, requests which do not match any route are labelled as "unmatched"
+func Middleware() echo.MiddlewareFunc {
+	var once sync.Once
+	routeNames := make(map[string]string)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// all routes are registered by the time the first request comes
+			once.Do(func() {
+				for _, r := range c.Echo().Routes() {
+					routeNames[r.Method+" "+r.Path] = r.Name
+				}
+			})
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let error handler write the response, so the real status is observed
+				c.Error(err)
+			}
+
+			route, ok := routeNames[c.Request().Method+" "+c.Path()]
+			if !ok {
+				route = "unmatched"
+			}
+			HttpRequestDuration.
+				WithLabelValues(route, c.Request().Method, strconv.Itoa(c.Response().Status)).
+				Observe(time.Since(start).Seconds())
+
+			return nil
+		}
+	}
+}
+
+// CommandMonitor observes duration of every command sent to MongoDB
+func CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			MongoCommandDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			MongoCommandDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/event"
+
+	"github.com/mineroot/news/internal/metrics"
+)
+
+func scrape(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(metrics.Middleware())
+	e.GET("/posts/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}).Name = "view-post"
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/path", nil))
+
+	body := scrape(t)
+	assert.Contains(t, body, `news_http_request_duration_seconds_count{method="GET",route="view-post",status="200"} 1`)
+	assert.Contains(t, body, `news_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+}
+
+func TestCommandMonitor(t *testing.T) {
+	monitor := metrics.CommandMonitor()
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", Duration: time.Millisecond},
+	})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", Duration: time.Millisecond},
+	})
+
+	body := scrape(t)
+	assert.Contains(t, body, `news_mongo_command_duration_seconds_count{command="find",status="ok"} 1`)
+	assert.Contains(t, body, `news_mongo_command_duration_seconds_count{command="insert",status="error"} 1`)
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	// CSRFHeader is sent by htmx with every request, see hx-headers in templates.Layout
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFFormField is sent by forms submitted without htmx
+	CSRFFormField = "_csrf"
+	// CSRFContextKey is echo context key of the current token
+	CSRFContextKey = "csrf"
+)
+
+type CSRFConfig struct {
+	CookieSecure bool
+	// SkipRoutes neither check nor issue token, e.g. assets, probes or endpoints called by browser itself
+	SkipRoutes []string
+}
+
+// CSRF issues token in cookie and requires the same token in header or form field of every unsafe request
+func CSRF(cfg CSRFConfig) echo.MiddlewareFunc {
+	return middleware.CSRFWithConfig(middleware.CSRFConfig{
+		Skipper: func(c echo.Context) bool {
+			return slices.Contains(cfg.SkipRoutes, c.Path())
+		},
+		TokenLookup:    "header:" + CSRFHeader + ",form:" + CSRFFormField,
+		ContextKey:     CSRFContextKey,
+		CookieName:     "_csrf",
+		CookiePath:     "/",
+		CookieHTTPOnly: true,
+		CookieSecure:   cfg.CookieSecure,
+		CookieSameSite: http.SameSiteLaxMode,
+		// missing token is reported as bad request by echo, both cases are forbidden here
+		ErrorHandler: func(_ error, _ echo.Context) error {
+			return echo.NewHTTPError(
+				http.StatusForbidden,
+				"The page has expired or the request was sent from another site. Reload the page and try again.",
+			)
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/middlewares"
+)
+
+func TestCSRF(t *testing.T) {
+	e := echo.New()
+	e.Use(middlewares.CSRF(middlewares.CSRFConfig{SkipRoutes: []string{"/report"}}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get(middlewares.CSRFContextKey).(string))
+	})
+	e.POST("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.POST("/report", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	// token is issued by safe request
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	token := rec.Body.String()
+	require.NotEmpty(t, token)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, token, cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+
+	post := func(header, field string, cookie bool) int {
+		form := url.Values{}
+		if field != "" {
+			form.Set(middlewares.CSRFFormField, field)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		if header != "" {
+			req.Header.Set(middlewares.CSRFHeader, header)
+		}
+		if cookie {
+			req.AddCookie(cookies[0])
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusNoContent, post(token, "", true), "htmx header")
+	assert.Equal(t, http.StatusNoContent, post("", token, true), "form field")
+	assert.Equal(t, http.StatusForbidden, post("", "", true), "missing token")
+	assert.Equal(t, http.StatusForbidden, post("invalid", "", true), "invalid token")
+	assert.Equal(t, http.StatusForbidden, post(token, "", false), "missing cookie")
+
+	// skipped routes are not checked
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/report", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Result().Cookies())
+}
@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID accepts X-Request-ID from the client or generates a new one,
+// echoes it back and attaches a child logger with request_id field to the request context
+func RequestID(logger *zerolog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !isValidRequestID(id) {
+				id = generateRequestID()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+			l := logger.With().Str("request_id", id).Logger()
+			c.SetRequest(req.WithContext(l.WithContext(req.Context())))
+
+			return next(c)
+		}
+	}
+}
+
+type AccessLogConfig struct {
+	// SampleEvery logs only every n-th successful request, failed requests are always logged
+	SampleEvery int
+	// SkipPaths are never logged, e.g. health checks
+	SkipPaths []string
+}
+
+// AccessLog writes a structured log line per request using the logger from the request context
+func AccessLog(cfg AccessLogConfig) echo.MiddlewareFunc {
+	var counter atomic.Uint64
+	sampleEvery := uint64(max(cfg.SampleEvery, 1))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if slices.Contains(cfg.SkipPaths, c.Request().URL.Path) {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let error handler write the response, so the real status is logged
+				c.Error(err)
+			}
+
+			req := c.Request()
+			res := c.Response()
+			var event *zerolog.Event
+			logger := zerolog.Ctx(req.Context())
+			switch {
+			case res.Status >= 500:
+				event = logger.Error()
+			case res.Status >= 400:
+				event = logger.Warn()
+			case counter.Add(1)%sampleEvery != 0:
+				return nil
+			default:
+				event = logger.Info()
+			}
+			event.
+				Str("method", req.Method).
+				Str("path", req.URL.Path).
+				Str("route", c.Path()).
+				Int("status", res.Status).
+				Dur("latency", time.Since(start)).
+				Int64("bytes_in", req.ContentLength).
+				Int64("bytes_out", res.Size).
+				Str("remote_ip", c.RealIP()).
+				Str("user_agent", req.UserAgent()).
+				Msg("request")
+
+			return nil
+		}
+	}
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		// printable ASCII only, so the id is safe to log and echo back
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func generateRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
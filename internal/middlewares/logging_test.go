@@ -0,0 +1,116 @@
+package middlewares_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/middlewares"
+)
+
+func newServer(logs *bytes.Buffer, cfg middlewares.AccessLogConfig) *echo.Echo {
+	logger := zerolog.New(logs)
+	e := echo.New()
+	e.Use(middlewares.RequestID(&logger))
+	e.Use(middlewares.AccessLog(cfg))
+	e.GET("/", func(c echo.Context) error {
+		zerolog.Ctx(c.Request().Context()).Info().Msg("from handler")
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.ErrServiceUnavailable
+	})
+	e.HEAD("/health", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	return e
+}
+
+func logLines(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	e := newServer(&logs, middlewares.AccessLogConfig{})
+
+	// generated id
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Len(t, id, 32)
+	lines := logLines(t, &logs)
+	require.Len(t, lines, 2)
+	assert.Equal(t, id, lines[0]["request_id"]) // handler log line
+	assert.Equal(t, id, lines[1]["request_id"]) // access log line
+
+	// id from client
+	logs.Reset()
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "client-id-123")
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "client-id-123", rec.Header().Get(echo.HeaderXRequestID))
+
+	// invalid id from client is replaced
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "bad id\n")
+	e.ServeHTTP(rec, req)
+	assert.Len(t, rec.Header().Get(echo.HeaderXRequestID), 32)
+}
+
+func TestAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	e := newServer(&logs, middlewares.AccessLogConfig{SkipPaths: []string{"/health"}})
+
+	// error status is logged
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	lines := logLines(t, &logs)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "error", lines[0]["level"])
+	assert.Equal(t, "GET", lines[0]["method"])
+	assert.Equal(t, "/fail", lines[0]["path"])
+	assert.Equal(t, "/fail", lines[0]["route"])
+	assert.EqualValues(t, http.StatusServiceUnavailable, lines[0]["status"])
+
+	// skipped path is not logged
+	logs.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodHead, "/health", nil))
+	assert.Empty(t, logs.String())
+}
+
+func TestAccessLog_Sampling(t *testing.T) {
+	var logs bytes.Buffer
+	e := newServer(&logs, middlewares.AccessLogConfig{SampleEvery: 3})
+
+	for range 6 {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	accessLines := 0
+	for _, line := range logLines(t, &logs) {
+		if line["message"] == "request" {
+			accessLines++
+		}
+	}
+	assert.Equal(t, 2, accessLines)
+}
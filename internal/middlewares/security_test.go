@@ -0,0 +1,87 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/middlewares"
+)
+
+func newSecureServer(cfg middlewares.SecurityHeadersConfig) *echo.Echo {
+	e := echo.New()
+	e.Use(middlewares.SecurityHeaders(cfg))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, templ.GetNonce(c.Request().Context()))
+	})
+	return e
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	e := newSecureServer(middlewares.SecurityHeadersConfig{
+		HSTSMaxAge:        365 * 24 * time.Hour,
+		HSTSPreload:       true,
+		CSPReportURI:      "/csp-report",
+		FrameAncestors:    []string{"'none'"},
+		ReferrerPolicy:    "same-origin",
+		PermissionsPolicy: "camera=()",
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	h := rec.Header()
+	assert.Equal(t, "nosniff", h.Get(echo.HeaderXContentTypeOptions))
+	assert.Equal(t, "DENY", h.Get(echo.HeaderXFrameOptions))
+	assert.Equal(t, "same-origin", h.Get(echo.HeaderReferrerPolicy))
+	assert.Equal(t, "camera=()", h.Get("Permissions-Policy"))
+	assert.Equal(t, `csp="/csp-report"`, h.Get("Reporting-Endpoints"))
+	assert.Empty(t, h.Get(echo.HeaderStrictTransportSecurity)) // plain http
+	assert.Empty(t, h.Get("Content-Security-Policy-Report-Only"))
+
+	// script nonce is in policy and in request context
+	csp := h.Get("Content-Security-Policy")
+	nonce := rec.Body.String()
+	require.NotEmpty(t, nonce)
+	assert.Contains(t, csp, "script-src 'nonce-"+nonce+"'")
+	assert.Contains(t, csp, "default-src 'none'")
+	assert.Contains(t, csp, "frame-ancestors 'none'")
+	assert.Contains(t, csp, "report-uri /csp-report")
+	assert.Regexp(t, regexp.MustCompile(`^[A-Za-z0-9+/]{22}==$`), nonce)
+
+	// nonce is unique per request
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotEqual(t, nonce, rec.Body.String())
+
+	// hsts over https behind proxy
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXForwardedProto, "https")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "max-age=31536000; preload", rec.Header().Get(echo.HeaderStrictTransportSecurity))
+}
+
+func TestSecurityHeaders_ReportOnly(t *testing.T) {
+	e := newSecureServer(middlewares.SecurityHeadersConfig{
+		CSPReportOnly:  true,
+		FrameAncestors: []string{"'self'", "https://example.com"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXForwardedProto, "https")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	h := rec.Header()
+	assert.Empty(t, h.Get("Content-Security-Policy"))
+	assert.Contains(t, h.Get("Content-Security-Policy-Report-Only"), "frame-ancestors 'self' https://example.com")
+	assert.NotContains(t, h.Get("Content-Security-Policy-Report-Only"), "report-uri")
+	assert.Empty(t, h.Get(echo.HeaderXFrameOptions))
+	assert.Empty(t, h.Get(echo.HeaderStrictTransportSecurity)) // disabled
+}
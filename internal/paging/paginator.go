@@ -5,11 +5,26 @@ import (
 	"strconv"
 )
 
+// Total is a number of items, it's only a lower bound when AtLeast is set, e.g. counting was stopped at some limit
+type Total struct {
+	Items   int
+	AtLeast bool
+}
+
+// String is e.g. "10" or "1000+"
+func (t Total) String() string {
+	if t.AtLeast {
+		return strconv.Itoa(t.Items) + "+"
+	}
+	return strconv.Itoa(t.Items)
+}
+
 type Paginator struct {
 	requestedPage int
 	realPage      int
 	size          int
 	pagesCount    int
+	total         Total
 }
 
 func NewPaginator(rawPage string, size int) (*Paginator, error) {
@@ -33,6 +48,7 @@ func NewPaginator(rawPage string, size int) (*Paginator, error) {
 }
 
 func (p *Paginator) SetRealItemsCount(itemsCount int) {
+	p.total = Total{Items: itemsCount}
 	if itemsCount == 0 {
 		p.pagesCount = 1
 		p.realPage = 1
@@ -49,6 +65,21 @@ func (p *Paginator) SetRealItemsCount(itemsCount int) {
 	}
 }
 
+// SetTotal is SetRealItemsCount, which keeps whether items count is exact
+func (p *Paginator) SetTotal(total Total) {
+	p.SetRealItemsCount(total.Items)
+	p.total = total
+}
+
+func (p *Paginator) Total() Total {
+	return p.total
+}
+
+// ManyPages reports whether there are more pages than PagesCount, but their number is unknown
+func (p *Paginator) ManyPages() bool {
+	return p.total.AtLeast
+}
+
 func (p *Paginator) NeedsRedirect() bool {
 	return p.requestedPage != p.realPage
 }
@@ -59,3 +59,16 @@ func TestSetRealItemsCount_ValidRange(t *testing.T) {
 	assert.Equal(t, 2, p.Page())
 	assert.False(t, p.NeedsRedirect())
 }
+
+func TestSetTotal_AtLeast(t *testing.T) {
+	p, _ := paging.NewPaginator("3", 10)
+	p.SetTotal(paging.Total{Items: 1000, AtLeast: true})
+	assert.Equal(t, 100, p.PagesCount())
+	assert.Equal(t, 3, p.Page())
+	assert.True(t, p.ManyPages())
+	assert.Equal(t, "1000+", p.Total().String())
+
+	p.SetRealItemsCount(25)
+	assert.False(t, p.ManyPages())
+	assert.Equal(t, "25", p.Total().String())
+}
@@ -0,0 +1,285 @@
+package postcache
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/mineroot/news/internal/metrics"
+	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/posts"
+)
+
+// Repository is the part of posts.Repository used by handlers
+type Repository interface {
+	FindAllByQueryWithPagination(ctx context.Context, paginator *paging.Paginator, query string) ([]*posts.Post, paging.Total, error)
+	FindById(ctx context.Context, id bson.ObjectID) (*posts.Post, error)
+	Create(ctx context.Context, post *posts.Post) (*posts.Post, error)
+	UpdateById(ctx context.Context, id bson.ObjectID, title, content string) (*posts.Post, error)
+	DeleteById(ctx context.Context, id bson.ObjectID) (*posts.Post, error)
+	RestoreById(ctx context.Context, id bson.ObjectID) (*posts.Post, error)
+}
+
+// Invalidation tells other replicas that cached posts are stale
+type Invalidation struct {
+	// Post is the changed post, listings are always invalidated
+	Post bson.ObjectID
+	// All drops every cached entry, e.g. when invalidations might have been missed
+	All bool
+	// Origin is the replica which has changed the post
+	Origin string
+}
+
+// Channel delivers invalidations between replicas
+type Channel interface {
+	Publish(ctx context.Context, inv Invalidation) error
+	// Subscribe calls fn for every invalidation published after subscription until ctx is cancelled
+	Subscribe(ctx context.Context, fn func(Invalidation)) error
+}
+
+type Config struct {
+	// Size is max number of cached listing pages and max number of cached posts
+	Size int
+	TTL  time.Duration
+	// Channel is optional, without it other replicas see changes after TTL
+	Channel Channel
+}
+
+type pageKey struct {
+	page, size int
+	query      string
+}
+
+type page struct {
+	posts []*posts.Post
+	total paging.Total
+}
+
+// Stats are cache lookups since start and number of cached entries
+type Stats struct {
+	PageHits, PageMisses uint64
+	PostHits, PostMisses uint64
+	Pages, Posts         int
+}
+
+// Cache is a read-through cache of posts.Repository, entries are dropped on every change of posts
+type Cache struct {
+	repo    Repository
+	channel Channel
+	origin  string
+
+	pages *lru[pageKey, page]
+	posts *lru[bson.ObjectID, *posts.Post]
+	group singleflight.Group
+	// generation is incremented on every invalidation, so results loaded before it are not cached
+	generation atomic.Uint64
+
+	pageHits, pageMisses atomic.Uint64
+	postHits, postMisses atomic.Uint64
+}
+
+func New(repo Repository, cfg Config) *Cache {
+	return &Cache{
+		repo:    repo,
+		channel: cfg.Channel,
+		origin:  rand.Text(),
+		pages:   newLRU[pageKey, page](cfg.Size, cfg.TTL, time.Now),
+		posts:   newLRU[bson.ObjectID, *posts.Post](cfg.Size, cfg.TTL, time.Now),
+	}
+}
+
+// SetClock replaces time.Now in tests
+func (c *Cache) SetClock(now func() time.Time) {
+	c.pages.now = now
+	c.posts.now = now
+}
+
+func (c *Cache) Stats() Stats {
+	return Stats{
+		PageHits:   c.pageHits.Load(),
+		PageMisses: c.pageMisses.Load(),
+		PostHits:   c.postHits.Load(),
+		PostMisses: c.postMisses.Load(),
+		Pages:      c.pages.len(),
+		Posts:      c.posts.len(),
+	}
+}
+
+func (c *Cache) FindAllByQueryWithPagination(
+	ctx context.Context,
+	paginator *paging.Paginator,
+	query string,
+) ([]*posts.Post, paging.Total, error) {
+	key := pageKey{page: paginator.Page(), size: paginator.Size(), query: query}
+	if p, ok := c.pages.get(key); ok {
+		c.hit("pages", &c.pageHits)
+		return clonePosts(p.posts), p.total, nil
+	}
+	c.miss("pages", &c.pageMisses)
+
+	generation := c.generation.Load()
+	flight := fmt.Sprintf("pages:%d:%d:%d:%s", generation, key.page, key.size, key.query)
+	v, err, _ := c.group.Do(flight, func() (any, error) {
+		// the call is shared, so it must not fail when the first caller goes away
+		found, total, err := c.repo.FindAllByQueryWithPagination(context.WithoutCancel(ctx), paginator, query)
+		if err != nil {
+			return nil, err
+		}
+		p := page{posts: found, total: total}
+		store(c, generation, c.pages, key, p)
+		return p, nil
+	})
+	if err != nil {
+		return nil, paging.Total{}, err
+	}
+	p := v.(page)
+	return clonePosts(p.posts), p.total, nil
+}
+
+// FindById returns posts.ErrNotFound if there is no post with such id, missing posts are not cached
+func (c *Cache) FindById(ctx context.Context, id bson.ObjectID) (*posts.Post, error) {
+	if post, ok := c.posts.get(id); ok {
+		c.hit("posts", &c.postHits)
+		return clonePost(post), nil
+	}
+	c.miss("posts", &c.postMisses)
+
+	generation := c.generation.Load()
+	flight := fmt.Sprintf("post:%d:%s", generation, id.Hex())
+	v, err, _ := c.group.Do(flight, func() (any, error) {
+		post, err := c.repo.FindById(context.WithoutCancel(ctx), id)
+		if err != nil {
+			return nil, err
+		}
+		store(c, generation, c.posts, id, post)
+		return post, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clonePost(v.(*posts.Post)), nil
+}
+
+func (c *Cache) Create(ctx context.Context, post *posts.Post) (*posts.Post, error) {
+	created, err := c.repo.Create(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	c.changed(ctx, bson.ObjectID{})
+	return created, nil
+}
+
+func (c *Cache) UpdateById(ctx context.Context, id bson.ObjectID, title, content string) (*posts.Post, error) {
+	updated, err := c.repo.UpdateById(ctx, id, title, content)
+	if err != nil {
+		return nil, err
+	}
+	c.changed(ctx, id)
+	return updated, nil
+}
+
+func (c *Cache) DeleteById(ctx context.Context, id bson.ObjectID) (*posts.Post, error) {
+	deleted, err := c.repo.DeleteById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.changed(ctx, id)
+	return deleted, nil
+}
+
+func (c *Cache) RestoreById(ctx context.Context, id bson.ObjectID) (*posts.Post, error) {
+	restored, err := c.repo.RestoreById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.changed(ctx, id)
+	return restored, nil
+}
+
+// Listen applies invalidations published by other replicas until ctx is cancelled
+func (c *Cache) Listen(ctx context.Context) error {
+	if c.channel == nil {
+		return nil
+	}
+	return c.channel.Subscribe(ctx, func(inv Invalidation) {
+		if inv.Origin == c.origin {
+			return
+		}
+		if inv.All {
+			c.invalidateAll()
+			return
+		}
+		c.invalidate(inv.Post)
+	})
+}
+
+// changed invalidates cache of this replica and notifies other ones, the change is already saved,
+// so failed notification is only logged
+func (c *Cache) changed(ctx context.Context, id bson.ObjectID) {
+	c.invalidate(id)
+	if c.channel == nil {
+		return
+	}
+	if err := c.channel.Publish(ctx, Invalidation{Post: id, Origin: c.origin}); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("unable to publish cache invalidation")
+	}
+}
+
+// invalidate drops all listings, as any change can move posts between pages, and the post if id is not zero
+func (c *Cache) invalidate(id bson.ObjectID) {
+	c.generation.Add(1)
+	c.pages.clear()
+	if !id.IsZero() {
+		c.posts.remove(id)
+	}
+}
+
+func (c *Cache) invalidateAll() {
+	c.generation.Add(1)
+	c.pages.clear()
+	c.posts.clear()
+}
+
+// store caches value loaded in generation, unless it has been invalidated meanwhile
+func store[K comparable, V any](c *Cache, generation uint64, l *lru[K, V], key K, value V) {
+	if c.generation.Load() != generation {
+		return
+	}
+	l.set(key, value)
+	// invalidation between the check and set
+	if c.generation.Load() != generation {
+		l.remove(key)
+	}
+}
+
+func (c *Cache) hit(cache string, counter *atomic.Uint64) {
+	counter.Add(1)
+	metrics.CacheRequests.WithLabelValues(cache, "hit").Inc()
+}
+
+func (c *Cache) miss(cache string, counter *atomic.Uint64) {
+	counter.Add(1)
+	metrics.CacheRequests.WithLabelValues(cache, "miss").Inc()
+}
+
+// clonePost protects cached post from changes made by callers
+func clonePost(post *posts.Post) *posts.Post {
+	clone := *post
+	clone.Tags = slices.Clone(post.Tags)
+	return &clone
+}
+
+func clonePosts(found []*posts.Post) []*posts.Post {
+	clones := make([]*posts.Post, len(found))
+	for i, post := range found {
+		clones[i] = clonePost(post)
+	}
+	return clones
+}
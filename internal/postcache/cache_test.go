@@ -0,0 +1,324 @@
+package postcache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/postcache"
+	"github.com/mineroot/news/internal/posts"
+)
+
+// fakeRepo keeps posts in memory and counts reads, release blocks reads until it's closed when it is set
+type fakeRepo struct {
+	mu      sync.Mutex
+	posts   map[bson.ObjectID]*posts.Post
+	finds   atomic.Int32
+	pages   atomic.Int32
+	release chan struct{}
+}
+
+func newFakeRepo(found ...*posts.Post) *fakeRepo {
+	r := &fakeRepo{posts: make(map[bson.ObjectID]*posts.Post)}
+	for _, post := range found {
+		r.posts[post.ID] = post
+	}
+	return r
+}
+
+func (r *fakeRepo) wait() {
+	if r.release != nil {
+		<-r.release
+	}
+}
+
+func (r *fakeRepo) FindAllByQueryWithPagination(_ context.Context, _ *paging.Paginator, _ string) ([]*posts.Post, paging.Total, error) {
+	r.pages.Add(1)
+	r.wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := make([]*posts.Post, 0, len(r.posts))
+	for _, post := range r.posts {
+		clone := *post
+		found = append(found, &clone)
+	}
+	return found, paging.Total{Items: len(found)}, nil
+}
+
+func (r *fakeRepo) FindById(_ context.Context, id bson.ObjectID) (*posts.Post, error) {
+	r.finds.Add(1)
+	r.wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, posts.ErrNotFound
+	}
+	clone := *post
+	return &clone, nil
+}
+
+func (r *fakeRepo) Create(_ context.Context, post *posts.Post) (*posts.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	post.ID = bson.NewObjectID()
+	r.posts[post.ID] = post
+	return post, nil
+}
+
+func (r *fakeRepo) UpdateById(_ context.Context, id bson.ObjectID, title, content string) (*posts.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, posts.ErrNotFound
+	}
+	post.Title, post.Content = title, content
+	return post, nil
+}
+
+func (r *fakeRepo) DeleteById(_ context.Context, id bson.ObjectID) (*posts.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, posts.ErrNotFound
+	}
+	delete(r.posts, id)
+	return post, nil
+}
+
+func (r *fakeRepo) RestoreById(_ context.Context, id bson.ObjectID) (*posts.Post, error) {
+	return nil, posts.ErrNotFound
+}
+
+func paginator(t *testing.T, page string) *paging.Paginator {
+	t.Helper()
+	p, err := paging.NewPaginator(page, 4)
+	require.NoError(t, err)
+	return p
+}
+
+func TestCache_FindById(t *testing.T) {
+	ctx := context.Background()
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "title", Tags: []string{"go"}}
+	repo := newFakeRepo(post)
+	cache := postcache.New(repo, postcache.Config{Size: 10, TTL: time.Minute})
+
+	found, err := cache.FindById(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "title", found.Title)
+	// callers get copies, so cached post can't be changed
+	found.Tags[0] = "changed"
+
+	found, err = cache.FindById(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go"}, found.Tags)
+	assert.EqualValues(t, 1, repo.finds.Load())
+
+	// missing posts are not cached
+	_, err = cache.FindById(ctx, bson.NewObjectID())
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+
+	stats := cache.Stats()
+	assert.EqualValues(t, 1, stats.PostHits)
+	assert.EqualValues(t, 2, stats.PostMisses)
+	assert.Equal(t, 1, stats.Posts)
+}
+
+func TestCache_FindAllByQueryWithPagination(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo(&posts.Post{ID: bson.NewObjectID(), Title: "title"})
+	cache := postcache.New(repo, postcache.Config{Size: 10, TTL: time.Minute})
+
+	for range 2 {
+		found, total, err := cache.FindAllByQueryWithPagination(ctx, paginator(t, "1"), "")
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+		assert.Equal(t, paging.Total{Items: 1}, total)
+	}
+	assert.EqualValues(t, 1, repo.pages.Load())
+
+	// page and query are parts of key
+	_, _, err := cache.FindAllByQueryWithPagination(ctx, paginator(t, "2"), "")
+	require.NoError(t, err)
+	_, _, err = cache.FindAllByQueryWithPagination(ctx, paginator(t, "1"), "query")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, repo.pages.Load())
+
+	stats := cache.Stats()
+	assert.EqualValues(t, 1, stats.PageHits)
+	assert.EqualValues(t, 3, stats.PageMisses)
+	assert.Equal(t, 3, stats.Pages)
+}
+
+func TestCache_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "title"}
+	other := &posts.Post{ID: bson.NewObjectID(), Title: "other"}
+	repo := newFakeRepo(post, other)
+	cache := postcache.New(repo, postcache.Config{Size: 10, TTL: time.Minute})
+	warm := func() {
+		_, _, err := cache.FindAllByQueryWithPagination(ctx, paginator(t, "1"), "")
+		require.NoError(t, err)
+		_, err = cache.FindById(ctx, post.ID)
+		require.NoError(t, err)
+		_, err = cache.FindById(ctx, other.ID)
+		require.NoError(t, err)
+	}
+
+	warm()
+	_, err := cache.UpdateById(ctx, post.ID, "updated", "")
+	require.NoError(t, err)
+	// listings and updated post are dropped, other posts are kept
+	assert.Equal(t, 0, cache.Stats().Pages)
+	assert.Equal(t, 1, cache.Stats().Posts)
+	found, err := cache.FindById(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "updated", found.Title)
+
+	warm()
+	_, err = cache.Create(ctx, &posts.Post{Title: "new"})
+	require.NoError(t, err)
+	found2, total, err := cache.FindAllByQueryWithPagination(ctx, paginator(t, "1"), "")
+	require.NoError(t, err)
+	assert.Len(t, found2, 3)
+	assert.Equal(t, paging.Total{Items: 3}, total)
+
+	warm()
+	_, err = cache.DeleteById(ctx, post.ID)
+	require.NoError(t, err)
+	_, err = cache.FindById(ctx, post.ID)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+	assert.Equal(t, 0, cache.Stats().Pages)
+}
+
+func TestCache_TTLAndSize(t *testing.T) {
+	ctx := context.Background()
+	a := &posts.Post{ID: bson.NewObjectID()}
+	b := &posts.Post{ID: bson.NewObjectID()}
+	repo := newFakeRepo(a, b)
+	cache := postcache.New(repo, postcache.Config{Size: 1, TTL: time.Minute})
+	now := time.Now()
+	cache.SetClock(func() time.Time { return now })
+
+	_, err := cache.FindById(ctx, a.ID)
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = cache.FindById(ctx, a.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, repo.finds.Load(), "expired post is loaded again")
+
+	// the least recently used post is evicted
+	_, err = cache.FindById(ctx, b.ID)
+	require.NoError(t, err)
+	_, err = cache.FindById(ctx, a.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, repo.finds.Load())
+	assert.Equal(t, 1, cache.Stats().Posts)
+}
+
+func TestCache_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	post := &posts.Post{ID: bson.NewObjectID()}
+	repo := newFakeRepo(post)
+	repo.release = make(chan struct{})
+	cache := postcache.New(repo, postcache.Config{Size: 10, TTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.FindById(ctx, post.ID)
+			assert.NoError(t, err)
+		}()
+	}
+	// let all callers join the first load
+	require.Eventually(t, func() bool { return cache.Stats().PostMisses == 10 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+	assert.EqualValues(t, 1, repo.finds.Load())
+}
+
+func TestCache_InvalidationDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "title"}
+	repo := newFakeRepo(post)
+	repo.release = make(chan struct{})
+	cache := postcache.New(repo, postcache.Config{Size: 10, TTL: time.Minute})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := cache.FindById(ctx, post.ID)
+		assert.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool { return repo.finds.Load() == 1 }, time.Second, time.Millisecond)
+	_, err := cache.UpdateById(ctx, post.ID, "updated", "")
+	require.NoError(t, err)
+	close(repo.release)
+	<-done
+
+	// result loaded before update is not cached
+	assert.Equal(t, 0, cache.Stats().Posts)
+}
+
+// fakeChannel delivers invalidations to all subscribers synchronously
+type fakeChannel struct {
+	mu          sync.Mutex
+	subscribers []func(postcache.Invalidation)
+}
+
+func (ch *fakeChannel) Publish(_ context.Context, inv postcache.Invalidation) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	for _, fn := range ch.subscribers {
+		fn(inv)
+	}
+	return nil
+}
+
+func (ch *fakeChannel) Subscribe(ctx context.Context, fn func(postcache.Invalidation)) error {
+	ch.mu.Lock()
+	ch.subscribers = append(ch.subscribers, fn)
+	ch.mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+func TestCache_Listen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "title"}
+	repo := newFakeRepo(post)
+	channel := &fakeChannel{}
+	// replicas share database
+	first := postcache.New(repo, postcache.Config{Size: 10, TTL: time.Minute, Channel: channel})
+	second := postcache.New(repo, postcache.Config{Size: 10, TTL: time.Minute, Channel: channel})
+	for _, cache := range []*postcache.Cache{first, second} {
+		go func() { _ = cache.Listen(ctx) }()
+	}
+	require.Eventually(t, func() bool {
+		channel.mu.Lock()
+		defer channel.mu.Unlock()
+		return len(channel.subscribers) == 2
+	}, time.Second, time.Millisecond)
+
+	_, err := second.FindById(ctx, post.ID)
+	require.NoError(t, err)
+	_, err = first.UpdateById(ctx, post.ID, "updated", "")
+	require.NoError(t, err)
+
+	found, err := second.FindById(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "updated", found.Title)
+}
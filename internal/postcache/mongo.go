@@ -0,0 +1,113 @@
+package postcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoChannel publishes invalidations into a capped collection, every replica tails it
+type MongoChannel struct {
+	collection *mongo.Collection
+	// RetryDelay is a pause before cursor is reopened, e.g. when collection is empty or mongo is unavailable
+	RetryDelay time.Duration
+}
+
+func NewMongoChannel(collection *mongo.Collection) *MongoChannel {
+	return &MongoChannel{collection: collection, RetryDelay: time.Second}
+}
+
+// clockSkew is max clock difference between replicas, it's included in object ids
+const clockSkew = 5 * time.Second
+
+type mongoInvalidation struct {
+	ID     bson.ObjectID `bson:"_id,omitempty"`
+	Post   bson.ObjectID `bson:"post,omitempty"`
+	All    bool          `bson:"all,omitempty"`
+	Origin string        `bson:"origin"`
+}
+
+func (ch *MongoChannel) Publish(ctx context.Context, inv Invalidation) error {
+	_, err := ch.collection.InsertOne(ctx, mongoInvalidation{Post: inv.Post, All: inv.All, Origin: inv.Origin})
+	return err
+}
+
+// Subscribe tails the collection. Invalidations published while mongo is unavailable may be missed,
+// so fn gets Invalidation with All after cursor failure
+func (ch *MongoChannel) Subscribe(ctx context.Context, fn func(Invalidation)) error {
+	logger := zerolog.Ctx(ctx)
+
+	// only invalidations published after subscription are delivered, otherwise old ones are just repeated,
+	// though ones published by replicas with clock behind are missed until cursor is reopened
+	last, err := ch.lastID(ctx)
+	if err != nil {
+		logger.Warn().Err(err).Msg("unable to find last cache invalidation")
+	}
+	var skew time.Duration
+	failed := false
+	for {
+		// tailable cursor dies when collection is empty, then it's just reopened
+		last, err = ch.tail(ctx, last, skew, failed, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		// ids of different replicas are not ordered, so reopened cursor repeats recent invalidations
+		// not to miss ones published by replicas with clock behind, repeating is harmless
+		skew = clockSkew
+		failed = err != nil
+		if failed {
+			logger.Warn().Err(err).Msg("cache invalidation cursor failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(ch.RetryDelay):
+		}
+	}
+}
+
+// tail delivers invalidations published after last minus skew until cursor dies, it returns the last delivered one
+func (ch *MongoChannel) tail(ctx context.Context, last bson.ObjectID, skew time.Duration, failed bool, fn func(Invalidation)) (bson.ObjectID, error) {
+	filter := bson.D{}
+	if !last.IsZero() {
+		since := last
+		if skew > 0 {
+			since = bson.NewObjectIDFromTimestamp(last.Timestamp().Add(-skew))
+		}
+		filter = bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: since}}}}
+	}
+	opts := options.Find().SetCursorType(options.TailableAwait).SetMaxAwaitTime(time.Second)
+	cursor, err := ch.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return last, err
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	if failed {
+		fn(Invalidation{All: true})
+	}
+	for cursor.Next(ctx) {
+		var inv mongoInvalidation
+		if err := cursor.Decode(&inv); err != nil {
+			return last, err
+		}
+		last = inv.ID
+		fn(Invalidation{Post: inv.Post, All: inv.All, Origin: inv.Origin})
+	}
+	return last, cursor.Err()
+}
+
+func (ch *MongoChannel) lastID(ctx context.Context) (bson.ObjectID, error) {
+	var inv mongoInvalidation
+	opts := options.FindOne().SetSort(bson.D{{Key: "$natural", Value: -1}})
+	err := ch.collection.FindOne(ctx, bson.D{}, opts).Decode(&inv)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return bson.ObjectID{}, nil
+	}
+	return inv.ID, err
+}
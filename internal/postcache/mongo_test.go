@@ -0,0 +1,61 @@
+package postcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/dbtest"
+	"github.com/mineroot/news/internal/postcache"
+)
+
+func TestMongoChannel(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.MigratedMongo(t)
+
+	channel := postcache.NewMongoChannel(db.GetCacheInvalidationsCollection(mongoClient, db.ClientConfig{}))
+	channel.RetryDelay = 10 * time.Millisecond
+	// published before subscription, it's not delivered
+	require.NoError(t, channel.Publish(ctx, postcache.Invalidation{Post: bson.NewObjectID(), Origin: "old"}))
+
+	subCtx, cancel := context.WithCancel(ctx)
+	received := make(chan postcache.Invalidation, 10)
+	done := make(chan error)
+	go func() {
+		done <- channel.Subscribe(subCtx, func(inv postcache.Invalidation) { received <- inv })
+	}()
+
+	id := bson.NewObjectID()
+	require.Eventually(t, func() bool {
+		// subscription may start after publishing, then invalidation is published again
+		if err := channel.Publish(ctx, postcache.Invalidation{Post: id, Origin: "replica"}); err != nil {
+			return false
+		}
+		select {
+		case inv := <-received:
+			assert.Equal(t, postcache.Invalidation{Post: id, Origin: "replica"}, inv)
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 10*time.Second, time.Millisecond)
+
+	require.NoError(t, channel.Publish(ctx, postcache.Invalidation{All: true, Origin: "posts import"}))
+	timeout := time.After(10 * time.Second)
+	for all := false; !all; {
+		select {
+		case inv := <-received:
+			all = inv == postcache.Invalidation{All: true, Origin: "posts import"}
+		case <-timeout:
+			t.Fatal("invalidation of all entries is not delivered")
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,146 @@
+package posts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/mineroot/news/internal/paging"
+)
+
+// strategies of counting posts on home page
+const (
+	// CountExact counts all posts with $facet on every request
+	CountExact = "exact"
+	// CountEstimated uses collection metadata, it's reused for Counting.Refresh
+	CountEstimated = "estimated"
+	// CountCounter reads counter document refreshed every Counting.Refresh by RefreshCounter
+	CountCounter = "counter"
+)
+
+// counterID is id of posts counter document in Counting.Counters collection
+const counterID = "posts"
+
+// Counting configures how totals of listings are counted, zero value counts exactly
+type Counting struct {
+	Home    string
+	Refresh time.Duration
+	// Counters is collection of counter document, it's required by CountCounter
+	Counters *mongo.Collection
+	// SearchLimit stops counting search results, so it's "1000+ results", 0 counts all results
+	SearchLimit int
+}
+
+// estimate is estimated posts count reused until expiration
+type estimate struct {
+	mu      sync.Mutex
+	count   int
+	expires time.Time
+}
+
+// SetCounting replaces exact counting of listing totals
+func (r *Repository) SetCounting(counting Counting) {
+	r.counting = counting
+}
+
+// exactCount reports whether total of listing is counted along with the page
+func (r *Repository) exactCount(query string) bool {
+	if query != "" {
+		return r.counting.SearchLimit == 0
+	}
+	return r.counting.Home == "" || r.counting.Home == CountExact
+}
+
+// count counts listing total with configured strategy
+func (r *Repository) count(ctx context.Context, paginator *paging.Paginator, query string) (paging.Total, error) {
+	if query != "" {
+		return r.countSearch(ctx, paginator, query)
+	}
+	if r.counting.Home == CountCounter {
+		count, err := r.readCounter(ctx)
+		if err == nil {
+			return paging.Total{Items: count}, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return paging.Total{}, err
+		}
+		// counter is not refreshed yet
+	}
+	count, err := r.estimatedCount(ctx)
+	return paging.Total{Items: count}, err
+}
+
+// countSearch counts matching posts up to the limit, but at least one post after the page, so next page is known to exist
+func (r *Repository) countSearch(ctx context.Context, paginator *paging.Paginator, query string) (paging.Total, error) {
+	limit := max(r.counting.SearchLimit, paginator.Page()*paginator.Size()+1)
+	filter := bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: query}}}}
+	count, err := r.collection.CountDocuments(ctx, filter, options.Count().SetLimit(int64(limit)))
+	if err != nil {
+		return paging.Total{}, err
+	}
+	return paging.Total{Items: int(count), AtLeast: int(count) == limit}, nil
+}
+
+func (r *Repository) estimatedCount(ctx context.Context) (int, error) {
+	r.estimate.mu.Lock()
+	defer r.estimate.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(r.estimate.expires) {
+		return r.estimate.count, nil
+	}
+	count, err := r.collection.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return 0, err
+	}
+	r.estimate.count = int(count)
+	r.estimate.expires = now.Add(r.counting.Refresh)
+	return r.estimate.count, nil
+}
+
+func (r *Repository) readCounter(ctx context.Context) (int, error) {
+	var counter struct {
+		Count int `bson:"count"`
+	}
+	err := r.counting.Counters.FindOne(ctx, bson.D{{Key: "_id", Value: counterID}}).Decode(&counter)
+	return counter.Count, err
+}
+
+// RefreshCounter counts all posts into counter document used by CountCounter
+func (r *Repository) RefreshCounter(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "posts.RefreshCounter")
+	defer func() { endSpan(span, err) }()
+
+	count, err := r.collection.CountDocuments(ctx, bson.D{})
+	if err != nil {
+		return mapError(err)
+	}
+	_, err = r.counting.Counters.UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: counterID}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "count", Value: count}, {Key: "refreshedAt", Value: time.Now()}}}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return mapError(err)
+}
+
+// RefreshCounterEvery calls RefreshCounter every Counting.Refresh until ctx is cancelled, failures are only logged
+func (r *Repository) RefreshCounterEvery(ctx context.Context) {
+	ticker := time.NewTicker(r.counting.Refresh)
+	defer ticker.Stop()
+	for {
+		if err := r.RefreshCounter(ctx); err != nil && ctx.Err() == nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("unable to refresh posts counter")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package posts
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var (
+	ErrNotFound    = errors.New("post not found")
+	ErrConflict    = errors.New("post conflicts with existing one")
+	ErrValidation  = errors.New("post is invalid")
+	ErrUnavailable = errors.New("posts storage is unavailable")
+)
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError describes which fields of a post are invalid, it matches ErrValidation with errors.Is
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return ErrValidation.Error()
+	}
+	b := strings.Builder{}
+	b.WriteString(ErrValidation.Error())
+	b.WriteString(": ")
+	for i, f := range e.Fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(fmt.Sprintf("'%s' %s", f.Field, f.Message))
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// mongodb error codes
+const (
+	// documentValidationFailure is returned when document does not match collection schema
+	documentValidationFailure = 121
+	// indexNotFound is returned by search while full-text index is rebuilt
+	indexNotFound = 27
+)
+
+// mapError translates mongodb driver errors to domain errors
+func mapError(err error) error {
+	var we mongo.WriteException
+	var se mongo.ServerError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case errors.As(err, &we) && we.HasErrorCode(documentValidationFailure):
+		return &ValidationError{Fields: schemaFieldErrors(we)}
+	case errors.As(err, &se) && se.HasErrorCode(indexNotFound),
+		mongo.IsTimeout(err), mongo.IsNetworkError(err),
+		errors.Is(err, context.DeadlineExceeded), errors.Is(err, mongo.ErrClientDisconnected):
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	default:
+		return err
+	}
+}
+
+// schemaError is errInfo of document validation failure, see
+// https://www.mongodb.com/docs/manual/core/schema-validation/handle-invalid-documents/
+type schemaError struct {
+	Details struct {
+		SchemaRulesNotSatisfied []struct {
+			PropertiesNotSatisfied []struct {
+				PropertyName string `bson:"propertyName"`
+				Details      []struct {
+					Reason string `bson:"reason"`
+				} `bson:"details"`
+			} `bson:"propertiesNotSatisfied"`
+			MissingProperties []string `bson:"missingProperties"`
+		} `bson:"schemaRulesNotSatisfied"`
+	} `bson:"details"`
+}
+
+// schemaFieldErrors describes fields violating $jsonSchema of collection, other validators are not described
+func schemaFieldErrors(we mongo.WriteException) []FieldError {
+	var fields []FieldError
+	for _, writeErr := range we.WriteErrors {
+		if writeErr.Code != documentValidationFailure {
+			continue
+		}
+		var info schemaError
+		if err := bson.Unmarshal(writeErr.Details, &info); err != nil {
+			continue
+		}
+		for _, rule := range info.Details.SchemaRulesNotSatisfied {
+			for _, property := range rule.PropertiesNotSatisfied {
+				if len(property.Details) == 0 {
+					fields = append(fields, FieldError{Field: property.PropertyName, Message: "is invalid"})
+				}
+				for _, detail := range property.Details {
+					fields = append(fields, FieldError{Field: property.PropertyName, Message: cmp.Or(detail.Reason, "is invalid")})
+				}
+			}
+			for _, name := range rule.MissingProperties {
+				fields = append(fields, FieldError{Field: name, Message: "is required"})
+			}
+		}
+	}
+	return fields
+}
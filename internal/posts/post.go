@@ -7,9 +7,12 @@ import (
 )
 
 type Post struct {
-	ID      bson.ObjectID `bson:"_id,omitempty"`
-	Title   string        `bson:"title"`
-	Content string        `bson:"content"`
-	Created time.Time     `bson:"createdAt"`
-	Updated time.Time     `bson:"updatedAt"`
+	ID      bson.ObjectID `bson:"_id,omitempty" json:"id,omitzero"`
+	Title   string        `bson:"title" json:"title"`
+	Content string        `bson:"content" json:"content"`
+	Tags    []string      `bson:"tags,omitempty" json:"tags,omitempty"`
+	// Slug is a human-readable identifier kept from imported content, it's not unique
+	Slug    string    `bson:"slug,omitempty" json:"slug,omitempty"`
+	Created time.Time `bson:"createdAt" json:"createdAt"`
+	Updated time.Time `bson:"updatedAt" json:"updatedAt"`
 }
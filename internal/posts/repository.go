@@ -5,29 +5,56 @@ import (
 	"errors"
 	"time"
 
+	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/tracing"
 )
 
+var tracer = otel.Tracer("github.com/mineroot/news/internal/posts")
+
 type Repository struct {
 	collection *mongo.Collection
+	trash      *mongo.Collection
+	counting   Counting
+	estimate   estimate
 }
 
-func NewRepository(collection *mongo.Collection) *Repository {
+func NewRepository(collection, trash *mongo.Collection) *Repository {
 	return &Repository{
 		collection: collection,
+		trash:      trash,
 	}
 }
 
+// trashedPost is a deleted post kept in trash collection, so deletion can be undone
+type trashedPost struct {
+	Post    `bson:",inline"`
+	Deleted time.Time `bson:"deletedAt"`
+}
+
 func (r *Repository) FindAllByQueryWithPagination(
 	ctx context.Context,
 	paginator *paging.Paginator,
 	query string,
-) ([]*Post, int, error) {
+) (_ []*Post, _ paging.Total, err error) {
+	ctx, span := tracer.Start(ctx, "posts.FindAllByQueryWithPagination", trace.WithAttributes(
+		attribute.Bool("posts.search", query != ""),
+		attribute.Int("posts.page", paginator.Page()),
+	))
+	defer func() { endSpan(span, err) }()
+
 	skip := (paginator.Page() - 1) * paginator.Size()
+	zerolog.Ctx(ctx).Debug().
+		Str("query", query).
+		Int("page", paginator.Page()).
+		Msg("finding posts page")
 	var pipeline mongo.Pipeline
 	if query != "" {
 		pipeline = mongo.Pipeline{
@@ -37,34 +64,45 @@ func (r *Repository) FindAllByQueryWithPagination(
 			{{"$sort", bson.D{
 				{"score", bson.D{{"$meta", "textScore"}}}, // sort by relevance
 			}}},
-			{{"$facet", bson.D{ // count total records & apply pagination
-				{"metadata", bson.A{
-					bson.D{{"$count", "totalCount"}},
-				}},
-				{"data", bson.A{
-					bson.D{{"$skip", skip}},
-					bson.D{{"$limit", paginator.Size()}},
-				}},
-			}}},
 		}
 	} else {
 		pipeline = mongo.Pipeline{
 			{{"$sort", bson.D{{"createdAt", -1}}}}, // sort by creation date
-			{{"$facet", bson.D{ // count total records & apply pagination
-				{"metadata", bson.A{
-					bson.D{{"$count", "totalCount"}},
-				}},
-				{"data", bson.A{
-					bson.D{{"$skip", skip}},
-					bson.D{{"$limit", paginator.Size()}},
-				}},
-			}}},
 		}
 	}
+	page := mongo.Pipeline{
+		{{"$skip", skip}},
+		{{"$limit", paginator.Size()}},
+	}
+
+	if !r.exactCount(query) {
+		total, err := r.count(ctx, paginator, query)
+		if err != nil {
+			return nil, paging.Total{}, mapError(err)
+		}
+		// total is counted separately, so only the page is fetched
+		cursor, err := r.collection.Aggregate(ctx, append(pipeline, page...))
+		if err != nil {
+			return nil, paging.Total{}, mapError(err)
+		}
+		defer cursor.Close(ctx)
+		found := []*Post{}
+		if err := cursor.All(ctx, &found); err != nil {
+			return nil, paging.Total{}, mapError(err)
+		}
+		return found, total, nil
+	}
+
+	pipeline = append(pipeline, bson.D{{"$facet", bson.D{ // count total records & apply pagination
+		{"metadata", bson.A{
+			bson.D{{"$count", "totalCount"}},
+		}},
+		{"data", page},
+	}}})
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, 0, err
+		return nil, paging.Total{}, mapError(err)
 	}
 	defer cursor.Close(ctx)
 
@@ -76,11 +114,11 @@ func (r *Repository) FindAllByQueryWithPagination(
 	}
 
 	if err := cursor.All(ctx, &aggregatedResults); err != nil {
-		return nil, 0, err
+		return nil, paging.Total{}, mapError(err)
 	}
 
 	if len(aggregatedResults) == 0 {
-		return []*Post{}, 0, nil
+		return []*Post{}, paging.Total{}, nil
 	}
 
 	total := 0
@@ -88,33 +126,41 @@ func (r *Repository) FindAllByQueryWithPagination(
 		total = aggregatedResults[0].Metadata[0].TotalCount
 	}
 
-	return aggregatedResults[0].Data, total, nil
+	return aggregatedResults[0].Data, paging.Total{Items: total}, nil
 }
 
-func (r *Repository) FindById(ctx context.Context, id bson.ObjectID) (*Post, error) {
+// FindById returns ErrNotFound if there is no post with such id
+func (r *Repository) FindById(ctx context.Context, id bson.ObjectID) (_ *Post, err error) {
+	ctx, span := tracer.Start(ctx, "posts.FindById")
+	defer func() { endSpan(span, err) }()
+
 	var post Post
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&post)
+	err = r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&post)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, nil
-		}
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	return &post, nil
 }
 
-func (r *Repository) Create(ctx context.Context, post *Post) (*Post, error) {
+func (r *Repository) Create(ctx context.Context, post *Post) (_ *Post, err error) {
+	ctx, span := tracer.Start(ctx, "posts.Create")
+	defer func() { endSpan(span, err) }()
+
 	result, err := r.collection.InsertOne(ctx, post)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	post.ID = result.InsertedID.(bson.ObjectID)
 
 	return post, nil
 }
 
-func (r *Repository) UpdateById(ctx context.Context, id bson.ObjectID, title, content string) (*Post, error) {
+// UpdateById returns ErrNotFound if there is no post with such id
+func (r *Repository) UpdateById(ctx context.Context, id bson.ObjectID, title, content string) (_ *Post, err error) {
+	ctx, span := tracer.Start(ctx, "posts.UpdateById")
+	defer func() { endSpan(span, err) }()
+
 	filter := bson.M{"_id": id}
 	update := bson.M{
 		"$set": bson.M{
@@ -126,25 +172,136 @@ func (r *Repository) UpdateById(ctx context.Context, id bson.ObjectID, title, co
 
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	var updatedPost Post
-	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedPost)
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedPost)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, nil
-		}
-		return nil, err
+		return nil, mapError(err)
 	}
 
 	return &updatedPost, nil
 }
 
-func (r *Repository) DeleteById(ctx context.Context, id bson.ObjectID) (*Post, error) {
-	var deletedPost Post
-	err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&deletedPost)
+// DeleteById deletes post and moves it to trash, so it can be restored with RestoreById.
+// It returns ErrNotFound if there is no post with such id
+func (r *Repository) DeleteById(ctx context.Context, id bson.ObjectID) (_ *Post, err error) {
+	ctx, span := tracer.Start(ctx, "posts.DeleteById")
+	defer func() { endSpan(span, err) }()
+
+	post, err := r.FindById(ctx, id)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, nil
-		}
 		return nil, err
 	}
+
+	// copy to trash first, so post is never lost
+	opts := options.Replace().SetUpsert(true)
+	trashed := trashedPost{Post: *post, Deleted: time.Now()}
+	if _, err = r.trash.ReplaceOne(ctx, bson.M{"_id": id}, trashed, opts); err != nil {
+		return nil, mapError(err)
+	}
+
+	var deletedPost Post
+	err = r.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&deletedPost)
+	if err != nil {
+		return nil, mapError(err)
+	}
 	return &deletedPost, nil
 }
+
+// RestoreById moves post deleted by DeleteById back from trash.
+// It returns ErrNotFound if post is not in trash and ErrConflict if post with such id already exists
+func (r *Repository) RestoreById(ctx context.Context, id bson.ObjectID) (_ *Post, err error) {
+	ctx, span := tracer.Start(ctx, "posts.RestoreById")
+	defer func() { endSpan(span, err) }()
+
+	var trashed trashedPost
+	err = r.trash.FindOne(ctx, bson.M{"_id": id}).Decode(&trashed)
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	// insert first, so post stays in trash if it can't be restored
+	if _, err = r.collection.InsertOne(ctx, trashed.Post); err != nil {
+		return nil, mapError(err)
+	}
+	if _, err = r.trash.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return nil, mapError(err)
+	}
+	return &trashed.Post, nil
+}
+
+// ForEach calls fn for every post from the oldest to the newest, it stops on the first fn error
+func (r *Repository) ForEach(ctx context.Context, fn func(*Post) error) (err error) {
+	ctx, span := tracer.Start(ctx, "posts.ForEach")
+	defer func() { endSpan(span, err) }()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return mapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post Post
+		if err := cursor.Decode(&post); err != nil {
+			return mapError(err)
+		}
+		if err := fn(&post); err != nil {
+			return err
+		}
+	}
+	return mapError(cursor.Err())
+}
+
+// BulkResult is an outcome of BulkSave, Failed maps indexes of posts which were not saved to errors
+type BulkResult struct {
+	Inserted int
+	Replaced int
+	Failed   map[int]error
+}
+
+// BulkSave inserts posts in a single unordered bulk write. Posts without ID get a new one,
+// posts with ID replace existing ones, so importing the same posts again is idempotent
+func (r *Repository) BulkSave(ctx context.Context, posts []*Post) (_ BulkResult, err error) {
+	ctx, span := tracer.Start(ctx, "posts.BulkSave", trace.WithAttributes(attribute.Int("posts.count", len(posts))))
+	defer func() { endSpan(span, err) }()
+
+	models := make([]mongo.WriteModel, len(posts))
+	for i, post := range posts {
+		if post.ID.IsZero() {
+			post.ID = bson.NewObjectID()
+			models[i] = mongo.NewInsertOneModel().SetDocument(post)
+			continue
+		}
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: post.ID}}).
+			SetReplacement(post).
+			SetUpsert(true)
+	}
+
+	res, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	result := BulkResult{Failed: make(map[int]error)}
+	if res != nil {
+		result.Inserted = int(res.InsertedCount + res.UpsertedCount)
+		result.Replaced = int(res.MatchedCount)
+	}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && bwe.WriteConcernError == nil {
+		// other posts of the batch are saved
+		for _, we := range bwe.WriteErrors {
+			result.Failed[we.Index] = mapError(mongo.WriteException{WriteErrors: []mongo.WriteError{we.WriteError}})
+		}
+		return result, nil
+	}
+	if err != nil {
+		return result, mapError(err)
+	}
+	return result, nil
+}
+
+// endSpan ends repository span, missing post is an expected outcome rather than a failure
+func endSpan(span trace.Span, err error) {
+	if errors.Is(err, ErrNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -2,40 +2,32 @@ package posts_test
 
 import (
 	"context"
-	"fmt"
-	"os"
+	"strconv"
 	"testing"
 	"time"
 
-	"github.com/ory/dockertest/v3"
-	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
 	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/dbtest"
 	"github.com/mineroot/news/internal/paging"
 	"github.com/mineroot/news/internal/posts"
 )
 
-var mongoClient *mongo.Client
-var repo *posts.Repository
-
-func TestMain(m *testing.M) {
-	cleanup, err := testMain()
-	if err != nil {
-		cleanup()
-		_, _ = fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
-
-	code := m.Run()
-	cleanup()
-	os.Exit(code)
+// startMongo runs migrated mongodb in non-UTC time zone, so dates are checked to be stored in UTC
+func startMongo(t *testing.T) (*mongo.Client, *posts.Repository) {
+	t.Helper()
+	mongoClient := dbtest.MigratedMongo(t, "TZ=Europe/Kyiv")
+	repo := posts.NewRepository(db.GetPostsCollection(mongoClient, db.ClientConfig{}), db.GetTrashCollection(mongoClient, db.ClientConfig{}))
+	return mongoClient, repo
 }
 
 func TestRepository_CRUD(t *testing.T) {
+	_, repo := startMongo(t)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
@@ -43,9 +35,8 @@ func TestRepository_CRUD(t *testing.T) {
 	oid := bson.NewObjectIDFromTimestamp(now)
 
 	// check post with defined id does not exist
-	notFound, err := repo.FindById(ctx, oid)
-	require.NoError(t, err)
-	assert.Nil(t, notFound)
+	_, err := repo.FindById(ctx, oid)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
 
 	// create post with defined id
 	expected := &posts.Post{
@@ -77,12 +68,137 @@ func TestRepository_CRUD(t *testing.T) {
 	assert.NotNil(t, actual)
 
 	// check post with defined id does not exist
-	notFound, err = repo.FindById(ctx, oid)
+	_, err = repo.FindById(ctx, oid)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+
+	// check deleting and updating missing post
+	_, err = repo.DeleteById(ctx, oid)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+	_, err = repo.UpdateById(ctx, oid, "Title", "Content")
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+
+	// restore deleted post
+	restored, err := repo.RestoreById(ctx, oid)
+	require.NoError(t, err)
+	require.NotNil(t, restored)
+	assert.Equal(t, "Updated Title", restored.Title)
+	actual, err = repo.FindById(ctx, oid)
+	require.NoError(t, err)
+	assert.Equal(t, restored, actual)
+
+	// post can be restored only once
+	_, err = repo.RestoreById(ctx, oid)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+
+	// create post with the same id
+	_, err = repo.Create(ctx, expected)
+	assert.ErrorIs(t, err, posts.ErrConflict)
+
+	// cleanup
+	_, err = repo.DeleteById(ctx, oid)
+	require.NoError(t, err)
+}
+
+func TestRepository_RestoreConflict(t *testing.T) {
+	mongoClient, repo := startMongo(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	now := time.Now().In(time.UTC).Truncate(time.Millisecond)
+	post, err := repo.Create(ctx, &posts.Post{Title: "Deleted", Content: "Content", Created: now, Updated: now})
+	require.NoError(t, err)
+	_, err = repo.DeleteById(ctx, post.ID)
+	require.NoError(t, err)
+
+	// another post has taken the id, restore fails
+	_, err = repo.Create(ctx, &posts.Post{ID: post.ID, Title: "Taken", Content: "Content", Created: now, Updated: now})
+	require.NoError(t, err)
+	_, err = repo.RestoreById(ctx, post.ID)
+	assert.ErrorIs(t, err, posts.ErrConflict)
+
+	// deleted post is still in trash, it's restored when the id is free
+	var trashed posts.Post
+	err = db.GetTrashCollection(mongoClient, db.ClientConfig{}).FindOne(ctx, bson.M{"_id": post.ID}).Decode(&trashed)
+	require.NoError(t, err)
+	assert.Equal(t, "Deleted", trashed.Title)
+	_, err = db.GetPostsCollection(mongoClient, db.ClientConfig{}).DeleteOne(ctx, bson.M{"_id": post.ID})
+	require.NoError(t, err)
+	restored, err := repo.RestoreById(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Deleted", restored.Title)
+
+	_, err = repo.DeleteById(ctx, post.ID)
+	require.NoError(t, err)
+}
+
+func TestRepository_SchemaValidation(t *testing.T) {
+	mongoClient, _ := startMongo(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// collection with schema set by operator, the app doesn't define one
+	database := mongoClient.Database(db.DefaultDatabase)
+	err := database.CreateCollection(ctx, "posts_validated", options.CreateCollection().SetValidator(bson.M{
+		"$jsonSchema": bson.M{
+			"required":   bson.A{"title", "author"},
+			"properties": bson.M{"title": bson.M{"bsonType": "string", "maxLength": 5}},
+		},
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Collection("posts_validated").Drop(context.Background()) })
+	validated := posts.NewRepository(database.Collection("posts_validated"), db.GetTrashCollection(mongoClient, db.ClientConfig{}))
+
+	now := time.Now()
+	_, err = validated.Create(ctx, &posts.Post{Title: "Too long title", Content: "Content", Created: now, Updated: now})
+	require.ErrorIs(t, err, posts.ErrValidation)
+	var ve *posts.ValidationError
+	require.ErrorAs(t, err, &ve)
+	assert.ElementsMatch(t, []posts.FieldError{
+		{Field: "title", Message: "specified string length was not satisfied"},
+		{Field: "author", Message: "is required"},
+	}, ve.Fields)
+}
+
+func TestRepository_BulkSaveAndForEach(t *testing.T) {
+	mongoClient, repo := startMongo(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	now := time.Now().In(time.UTC).Truncate(time.Millisecond)
+	existing := &posts.Post{ID: bson.NewObjectID(), Title: "Old", Content: "Old", Created: now, Updated: now}
+	batch := []*posts.Post{
+		{Title: "New", Content: "New", Tags: []string{"a"}, Created: now, Updated: now},
+		{ID: existing.ID, Title: "Replaced", Content: "Replaced", Created: now.Add(time.Second), Updated: now},
+	}
+
+	// first import inserts both
+	result, err := repo.BulkSave(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Inserted)
+	assert.Empty(t, result.Failed)
+	assert.False(t, batch[0].ID.IsZero())
+
+	// second import replaces post with id only
+	batch[0].ID = bson.NilObjectID
+	result, err = repo.BulkSave(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Inserted)
+	assert.Equal(t, 1, result.Replaced)
+
+	var titles []string
+	require.NoError(t, repo.ForEach(ctx, func(post *posts.Post) error {
+		titles = append(titles, post.Title)
+		return nil
+	}))
+	assert.Equal(t, []string{"New", "New", "Replaced"}, titles)
+
+	// cleanup
+	_, err = db.GetPostsCollection(mongoClient, db.ClientConfig{}).DeleteMany(ctx, bson.M{})
 	require.NoError(t, err)
-	assert.Nil(t, notFound)
 }
 
 func TestRepository_FindAllByQueryWithPagination(t *testing.T) {
+	mongoClient, repo := startMongo(t)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
@@ -121,9 +237,9 @@ func TestRepository_FindAllByQueryWithPagination(t *testing.T) {
 	require.NoError(t, err)
 	foundPosts, actualTotalPosts, err := repo.FindAllByQueryWithPagination(ctx, paginator, "")
 	require.NoError(t, err)
-	assert.Equal(t, expectedTotalPosts, actualTotalPosts) // check total posts count
-	assert.Len(t, foundPosts, pageSize)                   // assert page is "full"
-	paginator.SetRealItemsCount(actualTotalPosts)
+	assert.Equal(t, paging.Total{Items: expectedTotalPosts}, actualTotalPosts) // check total posts count
+	assert.Len(t, foundPosts, pageSize)                                        // assert page is "full"
+	paginator.SetTotal(actualTotalPosts)
 	assert.Equal(t, 1, paginator.Page())
 	assert.False(t, paginator.NeedsRedirect())
 
@@ -132,9 +248,9 @@ func TestRepository_FindAllByQueryWithPagination(t *testing.T) {
 	require.NoError(t, err)
 	foundPosts, actualTotalPosts, err = repo.FindAllByQueryWithPagination(ctx, paginator, "")
 	require.NoError(t, err)
-	assert.Equal(t, expectedTotalPosts, actualTotalPosts) // should be the same
-	assert.Len(t, foundPosts, 0)                          // should be 0 as 99th page is too large
-	paginator.SetRealItemsCount(actualTotalPosts)
+	assert.Equal(t, paging.Total{Items: expectedTotalPosts}, actualTotalPosts) // should be the same
+	assert.Len(t, foundPosts, 0)                                               // should be 0 as 99th page is too large
+	paginator.SetTotal(actualTotalPosts)
 	assert.Equal(t, 4, paginator.Page())      // "the real last page" should be 4 as ceil(16 / 5) = 4
 	assert.True(t, paginator.NeedsRedirect()) // so we should redirect to page 4
 
@@ -148,35 +264,60 @@ func TestRepository_FindAllByQueryWithPagination(t *testing.T) {
 	assert.Equal(t, dummyPosts[1], foundPosts[1])
 
 	// cleanup
-	_, err = mongoClient.Database(db.Name).Collection(db.PostsCollection).DeleteMany(ctx, bson.M{})
+	_, err = db.GetPostsCollection(mongoClient, db.ClientConfig{}).DeleteMany(ctx, bson.M{})
 	require.NoError(t, err)
 }
 
-func testMain() (func(), error) {
-	cleanup := func() {}
-	pool, err := dockertest.NewPool("")
-	if err != nil {
-		return cleanup, fmt.Errorf("could not connect to docker: %w", err)
-	}
+func TestRepository_Counting(t *testing.T) {
+	mongoClient, repo := startMongo(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
 
-	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
-		Repository: "mongo",
-		Tag:        "8.0.9",
-		Env:        []string{"TZ=Europe/Kyiv"},
-	}, func(config *docker.HostConfig) {
-		config.AutoRemove = true
-		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
-	})
-	if err != nil {
-		return cleanup, fmt.Errorf("could not start resource: %w", err)
+	for _, title := range []string{"Alpha one", "Alpha two", "Alpha three", "Beta"} {
+		now := time.Now().In(time.UTC).Truncate(time.Millisecond)
+		_, err := repo.Create(ctx, &posts.Post{Title: title, Content: "content", Created: now, Updated: now})
+		require.NoError(t, err)
 	}
-	cleanup = func() { _ = pool.Purge(resource) }
-
-	mongoClient, err = db.CreateMongoClient(context.Background(), fmt.Sprintf("mongodb://localhost:%s", resource.GetPort("27017/tcp")))
-	if err != nil {
-		return cleanup, fmt.Errorf("could not connect to docker mongodb: %w", err)
+	newRepo := func(counting posts.Counting) *posts.Repository {
+		r := posts.NewRepository(db.GetPostsCollection(mongoClient, db.ClientConfig{}), db.GetTrashCollection(mongoClient, db.ClientConfig{}))
+		r.SetCounting(counting)
+		return r
+	}
+	total := func(r *posts.Repository, page, size int, query string) paging.Total {
+		t.Helper()
+		paginator, err := paging.NewPaginator(strconv.Itoa(page), size)
+		require.NoError(t, err)
+		_, total, err := r.FindAllByQueryWithPagination(ctx, paginator, query)
+		require.NoError(t, err)
+		return total
 	}
 
-	repo = posts.NewRepository(db.GetPostsCollection(mongoClient))
-	return cleanup, nil
+	// estimated count is reused until refresh
+	estimated := newRepo(posts.Counting{Home: posts.CountEstimated, Refresh: time.Minute})
+	assert.Equal(t, paging.Total{Items: 4}, total(estimated, 1, 2, ""))
+	now := time.Now()
+	_, err := repo.Create(ctx, &posts.Post{Title: "Gamma", Content: "content", Created: now, Updated: now})
+	require.NoError(t, err)
+	assert.Equal(t, paging.Total{Items: 4}, total(estimated, 1, 2, ""))
+
+	// counter document falls back to estimated count until it's refreshed
+	counter := newRepo(posts.Counting{Home: posts.CountCounter, Counters: db.GetCountersCollection(mongoClient, db.ClientConfig{})})
+	assert.Equal(t, paging.Total{Items: 5}, total(counter, 1, 2, ""))
+	require.NoError(t, counter.RefreshCounter(ctx))
+	_, err = repo.Create(ctx, &posts.Post{Title: "Delta", Content: "content", Created: now, Updated: now})
+	require.NoError(t, err)
+	assert.Equal(t, paging.Total{Items: 5}, total(counter, 1, 2, ""))
+	require.NoError(t, counter.RefreshCounter(ctx))
+	assert.Equal(t, paging.Total{Items: 6}, total(counter, 1, 2, ""))
+
+	// search counting stops at the limit, but after the next page
+	capped := newRepo(posts.Counting{SearchLimit: 2})
+	assert.Equal(t, paging.Total{Items: 2, AtLeast: true}, total(capped, 1, 1, "alpha"))
+	assert.Equal(t, paging.Total{Items: 3}, total(capped, 3, 1, "alpha"))
+
+	// cleanup
+	_, err = db.GetPostsCollection(mongoClient, db.ClientConfig{}).DeleteMany(ctx, bson.M{})
+	require.NoError(t, err)
+	_, err = db.GetCountersCollection(mongoClient, db.ClientConfig{}).DeleteMany(ctx, bson.M{})
+	require.NoError(t, err)
 }
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket holding up to Requests tokens, refilled with Requests tokens per Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) IsZero() bool {
+	return l.Requests == 0
+}
+
+// rate is tokens refilled per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// retryAfter is how long it takes to refill the bucket with the single token
+func (l Limit) retryAfter(tokens float64) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / l.rate() * float64(time.Second)))
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// Result is outcome of taking a token
+type Result struct {
+	Allowed bool
+	// Remaining is number of whole tokens left in the bucket
+	Remaining int
+	// RetryAfter is how long to wait for the next token when request is not allowed
+	RetryAfter time.Duration
+}
+
+// Store takes a token from bucket of key
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// period refills empty bucket completely
+	period time.Duration
+}
+
+// MemoryStore keeps buckets of a single replica, use MongoStore to share limits between replicas
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// Now is the clock, replaced in tests
+	Now   func() time.Time
+	takes int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), Now: time.Now}
+}
+
+// sweepEvery is how often buckets are checked for removal, full buckets are equal to missing ones
+const sweepEvery = 1024
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), last: now, period: limit.Period}
+		s.buckets[key] = b
+	}
+	b.tokens = min(float64(limit.Requests), b.tokens+now.Sub(b.last).Seconds()*limit.rate())
+	b.last = now
+	if b.tokens < 1 {
+		return Result{RetryAfter: limit.retryAfter(b.tokens)}, nil
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// sweep removes buckets which are full by now
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
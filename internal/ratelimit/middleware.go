@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/mineroot/news/internal/metrics"
+)
+
+// UserContextKey is echo context key of authenticated user id, it's set by authentication
+const UserContextKey = "user_id"
+
+// KeyFunc identifies client, requests of the same client share bucket
+type KeyFunc func(c echo.Context) string
+
+// ByIP keys requests by client ip, it's taken from X-Forwarded-For only when request comes from trusted proxy,
+// see echo.IPExtractor
+func ByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// ByUserOrIP keys authenticated requests by user and anonymous ones by client ip, see ByIP
+func ByUserOrIP(c echo.Context) string {
+	if id, ok := c.Get(UserContextKey).(string); ok && id != "" {
+		return "user:" + id
+	}
+	return ByIP(c)
+}
+
+// Middleware rejects requests exceeding limit with 429 and Retry-After, name separates buckets of different limits.
+// Requests are allowed when store fails, so rate limiter never takes the app down
+func Middleware(store Store, name string, limit Limit, key KeyFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if limit.IsZero() {
+			return next
+		}
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			result, err := store.Take(ctx, name+":"+key(c), limit)
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Err(err).Str("limit", name).Msg("rate limit store failed, request is allowed")
+				return next(c)
+			}
+			if !result.Allowed {
+				metrics.RateLimited.WithLabelValues(name).Inc()
+				c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				return echo.ErrTooManyRequests
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoStore shares buckets between replicas, every bucket is a document updated atomically.
+// Expired buckets are removed by TTL index on expiresAt
+type MongoStore struct {
+	collection *mongo.Collection
+	// Now is the clock, replaced in tests
+	Now func() time.Time
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{collection: collection, Now: time.Now}
+}
+
+type mongoBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+func (s *MongoStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	b, err := s.take(ctx, key, limit)
+	// concurrent upsert of the same new key, the other one has created the document, so it's updated now
+	if mongo.IsDuplicateKeyError(err) {
+		b, err = s.take(ctx, key, limit)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if !b.Allowed {
+		return Result{RetryAfter: limit.retryAfter(b.Tokens)}, nil
+	}
+	return Result{Allowed: true, Remaining: int(b.Tokens)}, nil
+}
+
+// take updates bucket with a single upsert
+func (s *MongoStore) take(ctx context.Context, key string, limit Limit) (mongoBucket, error) {
+	now := s.Now()
+	burst := float64(limit.Requests)
+	// refill tokens for time elapsed since last take and take one if possible, all in a single update
+	refilled := bson.D{{Key: "$min", Value: bson.A{
+		burst,
+		bson.D{{Key: "$add", Value: bson.A{
+			bson.D{{Key: "$ifNull", Value: bson.A{"$tokens", burst}}},
+			bson.D{{Key: "$multiply", Value: bson.A{
+				bson.D{{Key: "$subtract", Value: bson.A{now, bson.D{{Key: "$ifNull", Value: bson.A{"$last", now}}}}}},
+				limit.rate() / 1000, // date difference is in milliseconds
+			}}},
+		}}},
+	}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "tokens", Value: refilled},
+			{Key: "last", Value: now},
+			{Key: "expiresAt", Value: now.Add(limit.Period)},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "allowed", Value: bson.D{{Key: "$gte", Value: bson.A{"$tokens", 1}}}},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "tokens", Value: bson.D{{Key: "$cond", Value: bson.A{"$allowed", bson.D{{Key: "$subtract", Value: bson.A{"$tokens", 1}}}, "$tokens"}}}},
+		}}},
+	}
+
+	var b mongoBucket
+	err := s.collection.FindOneAndUpdate(ctx,
+		bson.D{{Key: "_id", Value: key}},
+		pipeline,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&b)
+	return b, err
+}
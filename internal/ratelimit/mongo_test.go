@@ -0,0 +1,20 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/dbtest"
+	"github.com/mineroot/news/internal/ratelimit"
+)
+
+func TestMongoStore(t *testing.T) {
+	mongoClient := dbtest.MigratedMongo(t)
+
+	store := ratelimit.NewMongoStore(db.GetRateLimitsCollection(mongoClient, db.ClientConfig{}))
+	// mongodb stores dates with millisecond precision
+	now := time.Now().Truncate(time.Millisecond)
+	store.Now = func() time.Time { return now }
+	testStore(t, store, func(d time.Duration) { now = now.Add(d) })
+}
@@ -0,0 +1,112 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/ratelimit"
+)
+
+// testStore checks token bucket behaviour of store, clock is moved by advance
+func testStore(t *testing.T, store ratelimit.Store, advance func(time.Duration)) {
+	t.Helper()
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 3, Period: 3 * time.Second}
+
+	// bucket is full for a new key
+	for i := range 3 {
+		result, err := store.Take(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 2-i, result.Remaining)
+	}
+	result, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// other keys have own buckets
+	result, err = store.Take(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// bucket is refilled with one token per second
+	advance(500 * time.Millisecond)
+	result, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	advance(500 * time.Millisecond)
+	result, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// bucket is never filled above limit
+	advance(time.Hour)
+	result, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	now := time.Now()
+	store.Now = func() time.Time { return now }
+	testStore(t, store, func(d time.Duration) { now = now.Add(d) })
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("unavailable")
+}
+
+func TestMiddleware(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	e.GET("/", ok, ratelimit.Middleware(ratelimit.NewMemoryStore(), "test", limit, ratelimit.ByUserOrIP))
+	e.GET("/user", ok, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(ratelimit.UserContextKey, c.QueryParam("id"))
+			return next(c)
+		}
+	}, ratelimit.Middleware(ratelimit.NewMemoryStore(), "test", limit, ratelimit.ByUserOrIP))
+	e.GET("/disabled", ok, ratelimit.Middleware(ratelimit.NewMemoryStore(), "test", ratelimit.Limit{}, ratelimit.ByIP))
+	e.GET("/failing", ok, ratelimit.Middleware(failingStore{}, "test", limit, ratelimit.ByIP))
+
+	get := func(target, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// limited by ip
+	assert.Equal(t, http.StatusNoContent, get("/", "10.0.0.1").Code)
+	rec := get("/", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get(echo.HeaderRetryAfter))
+	assert.Equal(t, http.StatusNoContent, get("/", "10.0.0.2").Code)
+
+	// limited by user regardless of ip
+	assert.Equal(t, http.StatusNoContent, get("/user?id=1", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/user?id=1", "10.0.0.2").Code)
+	assert.Equal(t, http.StatusNoContent, get("/user?id=2", "10.0.0.2").Code)
+
+	// zero limit and failing store allow everything
+	for range 3 {
+		assert.Equal(t, http.StatusNoContent, get("/disabled", "10.0.0.1").Code)
+		assert.Equal(t, http.StatusNoContent, get("/failing", "10.0.0.1").Code)
+	}
+}
@@ -11,7 +11,17 @@ const (
 	CreatePost         = "/posts/new"
 	ViewCreatePostForm = "/posts/new"
 
-	DeletePost = "/posts/:id/delete"
+	DeletePost  = "/posts/:id/delete"
+	RestorePost = "/posts/:id/restore"
 
 	ViewSearch = "/search"
+
+	Asset = "/assets/:file"
+
+	CSPReport = "/csp-report"
+
+	Events = "/events"
+
+	ViewWebhookDeliveries = "/webhooks/deliveries"
+	RedeliverWebhook      = "/webhooks/deliveries/:id/redeliver"
 )
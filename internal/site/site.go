@@ -0,0 +1,237 @@
+package site
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+
+	"github.com/mineroot/news/internal/assets"
+	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/route"
+	"github.com/mineroot/news/templates"
+)
+
+const (
+	FeedFile = "feed.xml"
+
+	// DefaultFeedSize is how many latest posts are listed in the feed
+	DefaultFeedSize = 20
+)
+
+type PostsPaginator interface {
+	FindAllByQueryWithPagination(
+		ctx context.Context,
+		paginator *paging.Paginator,
+		query string,
+	) ([]*posts.Post, paging.Total, error)
+}
+
+// Report counts written files
+type Report struct {
+	Pages int
+	Posts int
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("pages: %d, posts: %d", r.Pages, r.Posts)
+}
+
+// Exporter renders home pages, post pages and RSS feed into plain files, links between them are relative,
+// so the directory can be served from any path or opened locally
+type Exporter struct {
+	repo     PostsPaginator
+	pageSize int
+	// BaseURL makes feed links absolute, e.g. https://example.com/news/, feed links are relative when it's empty
+	BaseURL  string
+	FeedSize int
+}
+
+func NewExporter(repo PostsPaginator, pageSize int) *Exporter {
+	return &Exporter{repo: repo, pageSize: pageSize, FeedSize: DefaultFeedSize}
+}
+
+// Export copies assets and walks all home pages in the same order as the app writing them into dir, dir is created if missing
+func (e *Exporter) Export(ctx context.Context, dir string) (Report, error) {
+	var report Report
+	var feed []*posts.Post
+	ctx = templates.WithStatic(ctx)
+
+	for _, asset := range assets.Default.All() {
+		err := writeFile(filepath.Join(dir, filepath.FromSlash(assetPath(asset.File))), func(f *os.File) error {
+			_, err := f.Write(asset.Content)
+			return err
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	for page, pagesCount := 1, 1; page <= pagesCount; page++ {
+		paginator, err := paging.NewPaginator(strconv.Itoa(page), e.pageSize)
+		if err != nil {
+			return report, err
+		}
+		pagePosts, total, err := e.repo.FindAllByQueryWithPagination(ctx, paginator, "")
+		if err != nil {
+			return report, fmt.Errorf("page %d: %w", page, err)
+		}
+		paginator.SetTotal(total)
+		pagesCount = paginator.PagesCount()
+
+		path := pagePath(page)
+		if err := writePage(ctx, dir, path, "Home", templates.Home(urls(path), pagePosts, paginator, "")); err != nil {
+			return report, err
+		}
+		report.Pages++
+
+		for _, post := range pagePosts {
+			path := postPath(post.ID.Hex())
+			if err := writePage(ctx, dir, path, post.Title, templates.Post(urls(path), post)); err != nil {
+				return report, err
+			}
+			report.Posts++
+			if len(feed) < e.FeedSize {
+				feed = append(feed, post)
+			}
+		}
+	}
+
+	return report, e.writeFeed(dir, feed)
+}
+
+func pagePath(page int) string {
+	if page == 1 {
+		return "index.html"
+	}
+	return fmt.Sprintf("page/%d.html", page)
+}
+
+func postPath(id string) string {
+	return fmt.Sprintf("posts/%s.html", id)
+}
+
+func assetPath(file string) string {
+	return "assets/" + file
+}
+
+// urls generates links relative to file at path, routes missing in static site link to nowhere
+func urls(path string) templates.UrlGenerator {
+	prefix := strings.Repeat("../", strings.Count(path, "/"))
+	return func(name string, params ...any) string {
+		switch name {
+		case route.ViewHome:
+			page := 1
+			if len(params) > 0 {
+				page, _ = params[0].(int)
+			}
+			return prefix + pagePath(page)
+		case route.ViewPost:
+			if len(params) > 0 {
+				return prefix + postPath(fmt.Sprint(params[0]))
+			}
+		case route.Asset:
+			if len(params) > 0 {
+				return prefix + assetPath(fmt.Sprint(params[0]))
+			}
+		}
+		return "#"
+	}
+}
+
+func writePage(ctx context.Context, dir, path, title string, content templ.Component) error {
+	return writeFile(filepath.Join(dir, filepath.FromSlash(path)), func(f *os.File) error {
+		return templates.Layout(urls(path), title, "", nil).Render(templ.WithChildren(ctx, content), f)
+	})
+}
+
+// writeFile creates file with parent directories and removes it if write fails
+func writeFile(path string, write func(f *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return f.Close()
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+}
+
+// writeFeed writes RSS 2.0 feed with latest posts
+func (e *Exporter) writeFeed(dir string, feed []*posts.Post) error {
+	base := e.BaseURL
+	if base != "" && !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	channel := rssChannel{
+		Title:       "Posts",
+		Link:        base + pagePath(1),
+		Description: "Latest posts",
+	}
+	var lastBuild time.Time
+	for _, post := range feed {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        base + postPath(post.ID.Hex()),
+			GUID:        rssGUID{Value: post.ID.Hex()},
+			PubDate:     post.Created.Format(time.RFC1123Z),
+			Description: post.Content,
+			Categories:  post.Tags,
+		})
+		if post.Updated.After(lastBuild) {
+			lastBuild = post.Updated
+		}
+	}
+	if !lastBuild.IsZero() {
+		channel.LastBuildDate = lastBuild.Format(time.RFC1123Z)
+	}
+
+	return writeFile(filepath.Join(dir, FeedFile), func(f *os.File) error {
+		if _, err := f.WriteString(xml.Header); err != nil {
+			return err
+		}
+		enc := xml.NewEncoder(f)
+		enc.Indent("", "  ")
+		return enc.Encode(rss{Version: "2.0", Channel: channel})
+	})
+}
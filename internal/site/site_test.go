@@ -0,0 +1,96 @@
+package site_test
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/assets"
+	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/site"
+)
+
+type fakeRepo []*posts.Post
+
+func (r fakeRepo) FindAllByQueryWithPagination(_ context.Context, paginator *paging.Paginator, _ string) ([]*posts.Post, paging.Total, error) {
+	from := min((paginator.Page()-1)*paginator.Size(), len(r))
+	to := min(from+paginator.Size(), len(r))
+	return r[from:to], paging.Total{Items: len(r)}, nil
+}
+
+func TestExporter_Export(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	var repo fakeRepo
+	for _, title := range []string{"First", "Second", "Third"} {
+		repo = append(repo, &posts.Post{ID: bson.NewObjectID(), Title: title, Content: "<b>" + title + "</b>", Created: now, Updated: now})
+	}
+	dir := t.TempDir()
+
+	exporter := site.NewExporter(repo, 2)
+	exporter.BaseURL = "https://example.com/news"
+	exporter.FeedSize = 2
+	report, err := exporter.Export(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, site.Report{Pages: 2, Posts: 3}, report)
+
+	// home pages link to each other and to posts relatively
+	index := readFile(t, dir, "index.html")
+	assert.Contains(t, index, `href="posts/`+repo[0].ID.Hex()+`.html"`)
+	assert.Contains(t, index, `href="page/2.html"`)
+	assert.NotContains(t, index, "posts/new")
+	assert.NotContains(t, index, "/search")
+	assert.NotContains(t, index, "htmx.org")
+	assert.NotContains(t, index, "sse-")
+	css := assets.Default.Get("app.css")
+	assert.Contains(t, index, `href="assets/`+css.File+`"`)
+	assert.FileExists(t, filepath.Join(dir, "assets", css.File))
+	second := readFile(t, dir, "page/2.html")
+	assert.Contains(t, second, `href="../index.html"`)
+	assert.Contains(t, second, `href="../assets/`+css.File+`"`)
+	assert.Contains(t, second, `href="../posts/`+repo[2].ID.Hex()+`.html"`)
+
+	// post pages have no editing UI and content is escaped
+	post := readFile(t, dir, "posts/"+repo[1].ID.Hex()+".html")
+	assert.Contains(t, post, "<title>Second</title>")
+	assert.Contains(t, post, "&lt;b&gt;Second&lt;/b&gt;")
+	assert.Contains(t, post, `href="../index.html"`)
+	assert.NotContains(t, post, "/edit")
+	assert.NotContains(t, post, "hx-confirm")
+	assert.NotContains(t, post, "sse-")
+
+	// feed lists latest posts with absolute links
+	var feed struct {
+		Items []struct {
+			Title string `xml:"title"`
+			Link  string `xml:"link"`
+		} `xml:"channel>item"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(readFile(t, dir, site.FeedFile)), &feed))
+	require.Len(t, feed.Items, 2)
+	assert.Equal(t, "First", feed.Items[0].Title)
+	assert.Equal(t, "https://example.com/news/posts/"+repo[0].ID.Hex()+".html", feed.Items[0].Link)
+}
+
+func TestExporter_ExportEmpty(t *testing.T) {
+	dir := t.TempDir()
+	report, err := site.NewExporter(fakeRepo{}, 2).Export(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, site.Report{Pages: 1}, report)
+	assert.Contains(t, readFile(t, dir, "index.html"), "No posts yet")
+	assert.FileExists(t, filepath.Join(dir, site.FeedFile))
+}
+
+func readFile(t *testing.T, dir, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+	require.NoError(t, err)
+	return string(content)
+}
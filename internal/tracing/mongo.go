@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const mongoScopeName = "github.com/mineroot/news/internal/tracing/mongo"
+
+type spanKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// CommandMonitor creates a client span for every command sent to MongoDB.
+// Command documents are never recorded, as they contain user data
+func CommandMonitor() *event.CommandMonitor {
+	var spans sync.Map // spanKey -> trace.Span
+
+	end := func(e event.CommandFinishedEvent, err error) {
+		v, ok := spans.LoadAndDelete(spanKey{e.ConnectionID, e.RequestID})
+		if !ok {
+			return
+		}
+		span := v.(trace.Span)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBNamespace(e.DatabaseName),
+				semconv.DBOperationName(e.CommandName),
+			}
+			if coll, ok := collectionName(e.Command); ok {
+				attrs = append(attrs, semconv.DBCollectionName(coll))
+			}
+			_, span := otel.Tracer(mongoScopeName).Start(ctx, "mongodb."+e.CommandName,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+			spans.Store(spanKey{e.ConnectionID, e.RequestID}, span)
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			end(e.CommandFinishedEvent, nil)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			end(e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
+
+// collectionName is the value of the first command element, e.g. {"find": "posts", ...}
+func collectionName(command bson.Raw) (string, bool) {
+	elems, err := command.Elements()
+	if err != nil || len(elems) == 0 {
+		return "", false
+	}
+	return elems[0].Value().StringValueOK()
+}
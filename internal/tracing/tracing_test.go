@@ -0,0 +1,91 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/mineroot/news/internal/middlewares"
+	"github.com/mineroot/news/internal/tracing"
+)
+
+func setupExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return exporter
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	exporter := setupExporter(t)
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	e := echo.New()
+	e.Use(middlewares.RequestID(&logger))
+	e.Use(otelecho.Middleware(tracing.ServiceName))
+	e.Use(tracing.LoggerMiddleware())
+	e.GET("/", func(c echo.Context) error {
+		zerolog.Ctx(c.Request().Context()).Info().Msg("from handler")
+		return c.NoContent(http.StatusOK)
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /", spans[0].Name)
+	assert.Contains(t, logs.String(), `"trace_id":"`+spans[0].SpanContext.TraceID().String()+`"`)
+}
+
+func TestCommandMonitor(t *testing.T) {
+	exporter := setupExporter(t)
+	monitor := tracing.CommandMonitor()
+
+	command, err := bson.Marshal(bson.D{{Key: "find", Value: "posts"}})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: command, DatabaseName: "news", CommandName: "find", RequestID: 1, ConnectionID: "conn",
+	})
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: command, DatabaseName: "news", CommandName: "find", RequestID: 2, ConnectionID: "conn",
+	})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, ConnectionID: "conn"},
+	})
+	monitor.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 2, ConnectionID: "conn"},
+		Failure:              errors.New("timeout"),
+	})
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "mongodb.find", spans[0].Name)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	attrs := make(map[string]string)
+	for _, attr := range spans[0].Attributes {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, "mongodb", attrs["db.system"])
+	assert.Equal(t, "posts", attrs["db.collection.name"])
+	assert.Equal(t, "news", attrs["db.namespace"])
+}
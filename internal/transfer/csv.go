@@ -0,0 +1,152 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+// csvHeader lists all columns, only title and content are required on import, columns may go in any order
+var csvHeader = []string{"id", "title", "content", "created", "updated", "tags", "slug"}
+
+// tags are joined into a single cell
+const csvTagsSeparator = ","
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvHeader, name) {
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"title", "content"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv column %q is missing", required)
+		}
+	}
+	return &csvReader{r: cr, columns: columns, line: 1}, nil
+}
+
+func (r *csvReader) Next() (*posts.Post, error) {
+	record, err := r.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		// field positions are unknown after parse error
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			r.line = pe.Line
+			return nil, &RecordError{Location: r.Location(), Err: err}
+		}
+		return nil, err
+	}
+	r.line, _ = r.r.FieldPos(0)
+
+	get := func(name string) string {
+		if i, ok := r.columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	post := &posts.Post{
+		Title:   get("title"),
+		Content: get("content"),
+		Slug:    get("slug"),
+	}
+	if tags := get("tags"); tags != "" {
+		post.Tags = splitTags(tags)
+	}
+	if id := get("id"); id != "" {
+		if post.ID, err = bson.ObjectIDFromHex(id); err != nil {
+			return nil, &RecordError{Location: r.Location(), Err: fmt.Errorf("invalid id: %w", err)}
+		}
+	}
+	if post.Created, err = parseTime(get("created")); err != nil {
+		return nil, &RecordError{Location: r.Location(), Err: fmt.Errorf("invalid created: %w", err)}
+	}
+	if post.Updated, err = parseTime(get("updated")); err != nil {
+		return nil, &RecordError{Location: r.Location(), Err: fmt.Errorf("invalid updated: %w", err)}
+	}
+	return post, nil
+}
+
+func (r *csvReader) Location() string {
+	return fmt.Sprintf("line %d", r.line)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (w *csvWriter) Write(post *posts.Post) error {
+	return w.w.Write([]string{
+		post.ID.Hex(),
+		post.Title,
+		post.Content,
+		formatTime(post.Created),
+		formatTime(post.Updated),
+		strings.Join(post.Tags, csvTagsSeparator),
+		post.Slug,
+	})
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, csvTagsSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseTime accepts RFC 3339 time, empty string is zero time
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
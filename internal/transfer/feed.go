@@ -0,0 +1,213 @@
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+// feedCategory is RSS category text or Atom category term attribute
+type feedCategory struct {
+	Domain string `xml:"domain,attr"`
+	Term   string `xml:"term,attr"`
+	Text   string `xml:",chardata"`
+}
+
+// rssItem is RSS 2.0 item, WordPress WXR export adds wp:* elements to it
+type rssItem struct {
+	Title          string         `xml:"title"`
+	Link           string         `xml:"link"`
+	GUID           string         `xml:"guid"`
+	PubDate        string         `xml:"pubDate"`
+	Description    string         `xml:"description"`
+	ContentEncoded string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories     []feedCategory `xml:"category"`
+
+	PostID     string `xml:"post_id"`
+	PostName   string `xml:"post_name"`
+	PostType   string `xml:"post_type"`
+	Status     string `xml:"status"`
+	PostDate   string `xml:"post_date_gmt"`
+	PostUpdate string `xml:"post_modified_gmt"`
+}
+
+type atomEntry struct {
+	Title     string         `xml:"title"`
+	ID        string         `xml:"id"`
+	Published string         `xml:"published"`
+	Updated   string         `xml:"updated"`
+	Content   string         `xml:"content"`
+	Summary   string         `xml:"summary"`
+	Category  []feedCategory `xml:"category"`
+}
+
+// feedReader streams items of RSS 2.0, Atom or WordPress WXR file, WXR is RSS with wp:* elements.
+// Only published posts of WXR are read, pages, attachments and drafts are skipped
+type feedReader struct {
+	dec  *xml.Decoder
+	item int
+}
+
+func newFeedReader(r io.Reader) *feedReader {
+	dec := xml.NewDecoder(r)
+	// feeds are often declared as ISO-8859-1 or windows-1251, but the content is read as is
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	return &feedReader{dec: dec}
+}
+
+func (r *feedReader) Next() (*posts.Post, error) {
+	for {
+		tok, err := r.dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Location(), err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "item":
+			r.item++
+			var item rssItem
+			if err := r.dec.DecodeElement(&item, &start); err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Location(), err)
+			}
+			if item.PostType != "" && (item.PostType != "post" || item.Status != "publish") {
+				continue
+			}
+			return r.rssPost(item)
+		case "entry":
+			r.item++
+			var entry atomEntry
+			if err := r.dec.DecodeElement(&entry, &start); err != nil {
+				return nil, fmt.Errorf("%s: %w", r.Location(), err)
+			}
+			return r.atomPost(entry)
+		}
+	}
+}
+
+func (r *feedReader) Location() string {
+	return fmt.Sprintf("item %d", r.item)
+}
+
+func (r *feedReader) rssPost(item rssItem) (*posts.Post, error) {
+	guid := cmpOr(item.GUID, item.Link, item.PostID)
+	if guid == "" {
+		return nil, &RecordError{Location: r.Location(), Err: errors.New("item has neither guid nor link")}
+	}
+
+	body := cmpOr(item.ContentEncoded, item.Description)
+	if item.PostType != "" {
+		// WXR content is exported before wpautop, paragraphs are separated by blank lines only
+		body = autop(body)
+	}
+	post := &posts.Post{
+		ID:      guidToID(guid),
+		Title:   strings.TrimSpace(html.UnescapeString(item.Title)),
+		Content: HTMLToText(body),
+		Slug:    item.PostName,
+	}
+	for _, c := range item.Categories {
+		// WXR has "category" and "post_tag" domains, both are tags here
+		post.Tags = appendTag(post.Tags, c.Text)
+	}
+
+	var err error
+	if item.PostDate != "" && !strings.HasPrefix(item.PostDate, "0000") {
+		post.Created, err = time.Parse(time.DateTime, item.PostDate)
+	} else if item.PubDate != "" {
+		post.Created, err = parseFeedTime(item.PubDate)
+	}
+	if err != nil {
+		return nil, &RecordError{Location: r.Location(), Err: fmt.Errorf("invalid publish date: %w", err)}
+	}
+	post.Updated = post.Created
+	if item.PostUpdate != "" && !strings.HasPrefix(item.PostUpdate, "0000") {
+		if updated, err := time.Parse(time.DateTime, item.PostUpdate); err == nil && updated.After(post.Created) {
+			post.Updated = updated
+		}
+	}
+	return post, nil
+}
+
+func (r *feedReader) atomPost(entry atomEntry) (*posts.Post, error) {
+	if entry.ID == "" {
+		return nil, &RecordError{Location: r.Location(), Err: errors.New("entry has no id")}
+	}
+	post := &posts.Post{
+		ID:      guidToID(entry.ID),
+		Title:   strings.TrimSpace(html.UnescapeString(entry.Title)),
+		Content: HTMLToText(cmpOr(entry.Content, entry.Summary)),
+	}
+	for _, c := range entry.Category {
+		post.Tags = appendTag(post.Tags, cmpOr(c.Term, c.Text))
+	}
+
+	var err error
+	if post.Created, err = parseFeedTime(cmpOr(entry.Published, entry.Updated)); err != nil {
+		return nil, &RecordError{Location: r.Location(), Err: fmt.Errorf("invalid publish date: %w", err)}
+	}
+	post.Updated = post.Created
+	if updated, err := parseFeedTime(entry.Updated); err == nil && updated.After(post.Created) {
+		post.Updated = updated
+	}
+	return post, nil
+}
+
+// guidToID derives post id from source guid, so importing the same item again replaces the post
+func guidToID(guid string) bson.ObjectID {
+	var id bson.ObjectID
+	sum := sha256.Sum256([]byte(strings.TrimSpace(guid)))
+	copy(id[:], sum[:])
+	return id
+}
+
+var feedTimeLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"}
+
+// parseFeedTime accepts RFC 822 dates used by RSS and RFC 3339 ones used by Atom, empty string is zero time
+func parseFeedTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	var err error
+	for _, layout := range feedTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func appendTag(tags []string, tag string) []string {
+	tag = strings.TrimSpace(html.UnescapeString(tag))
+	if tag == "" || strings.EqualFold(tag, "uncategorized") || slices.Contains(tags, tag) {
+		return tags
+	}
+	return append(tags, tag)
+}
+
+func cmpOr(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package transfer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/transfer"
+)
+
+const wxr = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<title>Old site</title>
+	<item>
+		<title>Hello &amp; welcome</title>
+		<link>https://example.com/hello</link>
+		<pubDate>Mon, 01 Jan 2018 10:00:00 +0000</pubDate>
+		<guid isPermaLink="false">https://example.com/?p=1</guid>
+		<content:encoded><![CDATA[<p>First <b>paragraph</b>.</p><ul><li>one</li><li>two</li></ul><script>alert(1)</script>]]></content:encoded>
+		<excerpt:encoded><![CDATA[Excerpt]]></excerpt:encoded>
+		<wp:post_id>1</wp:post_id>
+		<wp:post_date_gmt><![CDATA[2018-01-01 09:00:00]]></wp:post_date_gmt>
+		<wp:post_modified_gmt><![CDATA[2018-02-01 09:00:00]]></wp:post_modified_gmt>
+		<wp:post_name><![CDATA[hello]]></wp:post_name>
+		<wp:status><![CDATA[publish]]></wp:status>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+		<category domain="category" nicename="news"><![CDATA[News]]></category>
+		<category domain="post_tag" nicename="go"><![CDATA[Go]]></category>
+		<category domain="category" nicename="uncategorized"><![CDATA[Uncategorized]]></category>
+	</item>
+	<item>
+		<title>Draft</title>
+		<guid isPermaLink="false">https://example.com/?p=2</guid>
+		<wp:status><![CDATA[draft]]></wp:status>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+	</item>
+	<item>
+		<title>Logo</title>
+		<guid isPermaLink="false">https://example.com/logo.png</guid>
+		<wp:status><![CDATA[inherit]]></wp:status>
+		<wp:post_type><![CDATA[attachment]]></wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+func TestFeedReader_WXR(t *testing.T) {
+	r, err := transfer.NewReader(transfer.FormatWXR, strings.NewReader(wxr), "")
+	require.NoError(t, err)
+	read := readAll(t, r)
+	require.Len(t, read, 1) // draft and attachment are skipped
+
+	post := read[0]
+	assert.Equal(t, "Hello & welcome", post.Title)
+	assert.Equal(t, "First paragraph.\n\n- one\n- two", post.Content)
+	assert.Equal(t, []string{"News", "Go"}, post.Tags)
+	assert.Equal(t, "hello", post.Slug)
+	assert.Equal(t, time.Date(2018, 1, 1, 9, 0, 0, 0, time.UTC), post.Created)
+	assert.Equal(t, time.Date(2018, 2, 1, 9, 0, 0, 0, time.UTC), post.Updated)
+
+	// id is derived from guid, so import is idempotent
+	r, err = transfer.NewReader(transfer.FormatWXR, strings.NewReader(wxr), "")
+	require.NoError(t, err)
+	again := readAll(t, r)
+	assert.Equal(t, post.ID, again[0].ID)
+	assert.False(t, post.ID.IsZero())
+}
+
+func TestFeedReader_WXRWithoutParagraphs(t *testing.T) {
+	wxr := `<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:wp="http://wordpress.org/export/1.2/">
+	<channel><item>
+		<title>Plain</title>
+		<guid isPermaLink="false">https://example.com/?p=3</guid>
+		<content:encoded><![CDATA[First paragraph.
+
+Second <b>paragraph</b>.
+Line two.
+<ul>
+<li>one</li>
+<li>two</li>
+</ul>
+<pre>code
+
+  indented</pre>
+Last]]></content:encoded>
+		<wp:post_date_gmt><![CDATA[2018-01-01 09:00:00]]></wp:post_date_gmt>
+		<wp:status><![CDATA[publish]]></wp:status>
+		<wp:post_type><![CDATA[post]]></wp:post_type>
+	</item></channel>
+	</rss>`
+	r, err := transfer.NewReader(transfer.FormatWXR, strings.NewReader(wxr), "")
+	require.NoError(t, err)
+	read := readAll(t, r)
+	require.Len(t, read, 1)
+	assert.Equal(t, "First paragraph.\n\nSecond paragraph.\nLine two.\n\n- one\n- two\n\ncode\n\n  indented\n\nLast", read[0].Content)
+}
+
+func TestFeedReader_RSS(t *testing.T) {
+	rss := `<rss version="2.0"><channel>
+		<item>
+			<title>Partner news</title>
+			<link>https://partner.example/news/1</link>
+			<description>&lt;p&gt;Read &lt;a href="https://partner.example/more"&gt;more&lt;/a&gt;&lt;/p&gt;</description>
+			<pubDate>Tue, 10 Jun 2025 04:00:00 GMT</pubDate>
+			<category>World</category>
+		</item>
+		<item><title>Without guid</title></item>
+	</channel></rss>`
+	r, err := transfer.NewReader(transfer.FormatRSS, strings.NewReader(rss), "")
+	require.NoError(t, err)
+
+	post, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Partner news", post.Title)
+	assert.Equal(t, "Read more (https://partner.example/more)", post.Content)
+	assert.Equal(t, []string{"World"}, post.Tags)
+	assert.Equal(t, time.Date(2025, 6, 10, 4, 0, 0, 0, time.UTC), post.Created)
+
+	_, err = r.Next()
+	var re *transfer.RecordError
+	assert.ErrorAs(t, err, &re)
+}
+
+func TestFeedReader_Atom(t *testing.T) {
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom">
+		<entry>
+			<id>urn:uuid:60a76c80-d399-11d9-b93C-0003939e0af6</id>
+			<title>Atom entry</title>
+			<published>2024-03-01T12:00:00+02:00</published>
+			<updated>2024-03-02T12:00:00+02:00</updated>
+			<content type="html">&lt;h1&gt;Title&lt;/h1&gt;Text&lt;br/&gt;next line</content>
+			<category term="tech"/>
+		</entry>
+	</feed>`
+	r, err := transfer.NewReader(transfer.FormatRSS, strings.NewReader(atom), "")
+	require.NoError(t, err)
+	read := readAll(t, r)
+	require.Len(t, read, 1)
+	assert.Equal(t, "Title\n\nText\nnext line", read[0].Content)
+	assert.Equal(t, []string{"tech"}, read[0].Tags)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), read[0].Created)
+	assert.Equal(t, time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), read[0].Updated)
+
+	_, err = transfer.NewWriter(transfer.FormatRSS, nil, "")
+	assert.Error(t, err)
+}
+
+func TestHTMLToText(t *testing.T) {
+	assert.Equal(t, "a b", transfer.HTMLToText("  a\n\n   b "))
+	assert.Equal(t, "Tom & Jerry", transfer.HTMLToText("Tom &amp; Jerry"))
+	assert.Equal(t, "one\n\ntwo", transfer.HTMLToText("<p>one</p>\n\n\n<p>two</p>"))
+	assert.Equal(t, "code\n  indented", transfer.HTMLToText("<pre>code\n  indented</pre>"))
+	assert.Equal(t, "", transfer.HTMLToText("<style>p{}</style><script>x</script>"))
+}
@@ -0,0 +1,158 @@
+package transfer
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockElements start a new paragraph
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Blockquote: true, atom.Pre: true, atom.Table: true,
+	atom.Ul: true, atom.Ol: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Figure: true, atom.Hr: true,
+	atom.Section: true, atom.Article: true,
+}
+
+// skippedElements are dropped with their content
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Noscript: true,
+}
+
+var (
+	spaces          = regexp.MustCompile(`[ \t\r\n]+`)
+	emptyParagraphs = regexp.MustCompile(`\n{3,}`)
+	blankLines      = regexp.MustCompile(`\n[ \t]*\n\s*`)
+	preBlocks       = regexp.MustCompile(`(?is)<pre[\s>].*?</pre>`)
+	leadingTag      = regexp.MustCompile(`^\s*</?([a-zA-Z0-9]+)`)
+	trailingTag     = regexp.MustCompile(`</?([a-zA-Z0-9]+)[^<>]*>\s*$`)
+)
+
+// autop marks paragraphs of WordPress content the way wpautop does when a post is displayed:
+// blank lines separate paragraphs and single newlines are line breaks, except next to block tags and inside <pre>
+func autop(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var b strings.Builder
+	last := 0
+	for _, loc := range preBlocks.FindAllStringIndex(s, -1) {
+		b.WriteString(autopText(s[last:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(autopText(s[last:]))
+	return b.String()
+}
+
+func autopText(s string) string {
+	var b strings.Builder
+	for _, paragraph := range blankLines.Split(s, -1) {
+		lines := strings.Split(strings.TrimSpace(paragraph), "\n")
+		if lines[0] == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		for i, line := range lines {
+			if i > 0 {
+				if isBlockTag(trailingTag.FindStringSubmatch(lines[i-1])) || isBlockTag(leadingTag.FindStringSubmatch(line)) {
+					b.WriteString("\n")
+				} else {
+					b.WriteString("<br>\n")
+				}
+			}
+			b.WriteString(line)
+		}
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// isBlockTag reports whether submatch of leadingTag or trailingTag is a tag which is never followed by <br>
+func isBlockTag(match []string) bool {
+	if match == nil {
+		return false
+	}
+	a := atom.Lookup([]byte(strings.ToLower(match[1])))
+	return blockElements[a] || a == atom.Li || a == atom.Tr || a == atom.Td || a == atom.Th || a == atom.Br ||
+		a == atom.Thead || a == atom.Tbody || a == atom.Tfoot || a == atom.Figcaption
+}
+
+// HTMLToText converts HTML to plain text content, as posts are rendered as text.
+// Paragraphs are separated by empty lines, list items start with "- ", links keep their urls
+func HTMLToText(s string) string {
+	z := html.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	skip := 0
+	pre := 0
+	var hrefs []string
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			text := emptyParagraphs.ReplaceAllString(b.String(), "\n\n")
+			lines := strings.Split(text, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimRight(line, " ")
+			}
+			return strings.TrimSpace(strings.Join(lines, "\n"))
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := string(z.Text())
+			if pre == 0 {
+				text = spaces.ReplaceAllString(text, " ")
+				// avoid leading spaces at the beginning of a line
+				if str := b.String(); str == "" || strings.HasSuffix(str, "\n") {
+					text = strings.TrimLeft(text, " ")
+				}
+			}
+			b.WriteString(text)
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, hasAttr := z.TagName()
+			a := atom.Lookup(name)
+			start := tt != html.EndTagToken
+			switch {
+			case skippedElements[a]:
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			case a == atom.Br && start:
+				b.WriteString("\n")
+			case a == atom.Li && start:
+				b.WriteString("\n- ")
+			case a == atom.Pre:
+				if start {
+					pre++
+				} else if pre > 0 {
+					pre--
+				}
+				b.WriteString("\n\n")
+			case a == atom.A && tt == html.StartTagToken:
+				href := ""
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if string(key) == "href" {
+						href = string(val)
+					}
+				}
+				hrefs = append(hrefs, href)
+			case a == atom.A && tt == html.EndTagToken && len(hrefs) > 0:
+				href := hrefs[len(hrefs)-1]
+				hrefs = hrefs[:len(hrefs)-1]
+				if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+					b.WriteString(" (" + href + ")")
+				}
+			case a == atom.Tr:
+				b.WriteString("\n")
+			case blockElements[a]:
+				b.WriteString("\n\n")
+			}
+		}
+	}
+}
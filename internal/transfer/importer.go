@@ -0,0 +1,122 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+type BulkSaver interface {
+	BulkSave(ctx context.Context, posts []*posts.Post) (posts.BulkResult, error)
+}
+
+// Report summarizes import, Errors describe every post which was not saved
+type Report struct {
+	Read     int
+	Inserted int
+	Replaced int
+	Invalid  int
+	Failed   int
+	Errors   []error
+	DryRun   bool
+}
+
+func (r Report) String() string {
+	if r.DryRun {
+		return fmt.Sprintf("dry run: read %d, valid %d, invalid %d", r.Read, r.Read-r.Invalid, r.Invalid)
+	}
+	return fmt.Sprintf("read %d, inserted %d, replaced %d, invalid %d, failed %d", r.Read, r.Inserted, r.Replaced, r.Invalid, r.Failed)
+}
+
+type Importer struct {
+	repo BulkSaver
+	// Validate checks post before it's saved, invalid posts are reported and skipped
+	Validate func(post *posts.Post) error
+	// BatchSize is a number of posts sent to mongodb in a single bulk write
+	BatchSize int
+	// DryRun only reads and validates posts
+	DryRun bool
+}
+
+func NewImporter(repo BulkSaver) *Importer {
+	return &Importer{
+		repo:      repo,
+		Validate:  func(*posts.Post) error { return nil },
+		BatchSize: 500,
+	}
+}
+
+// Import streams posts from r in batches, missing timestamps are set to the current time.
+// It stops on read or storage errors only, malformed and invalid posts are reported
+func (i *Importer) Import(ctx context.Context, r Reader) (Report, error) {
+	report := Report{DryRun: i.DryRun}
+	batch := make([]*posts.Post, 0, i.BatchSize)
+	locations := make([]string, 0, i.BatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := i.repo.BulkSave(ctx, batch)
+		if err != nil {
+			return err
+		}
+		report.Inserted += result.Inserted
+		report.Replaced += result.Replaced
+		report.Failed += len(result.Failed)
+		for _, idx := range slices.Sorted(maps.Keys(result.Failed)) {
+			report.Errors = append(report.Errors, &RecordError{Location: locations[idx], Err: result.Failed[idx]})
+		}
+		batch, locations = batch[:0], locations[:0]
+		return nil
+	}
+
+	now := time.Now()
+	for {
+		post, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var re *RecordError
+		if errors.As(err, &re) {
+			report.Read++
+			report.Invalid++
+			report.Errors = append(report.Errors, re)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Read++
+
+		if err := i.Validate(post); err != nil {
+			report.Invalid++
+			report.Errors = append(report.Errors, &RecordError{Location: r.Location(), Err: err})
+			continue
+		}
+		if post.Created.IsZero() {
+			post.Created = now
+		}
+		if post.Updated.IsZero() {
+			post.Updated = post.Created
+		}
+		if i.DryRun {
+			continue
+		}
+
+		batch = append(batch, post)
+		locations = append(locations, r.Location())
+		if len(batch) >= i.BatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, flush()
+}
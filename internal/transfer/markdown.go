@@ -0,0 +1,158 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+const frontMatterDelimiter = "---"
+
+type frontMatter struct {
+	ID      string    `yaml:"id,omitempty"`
+	Title   string    `yaml:"title"`
+	Created time.Time `yaml:"created,omitempty"`
+	Updated time.Time `yaml:"updated,omitempty"`
+	Tags    []string  `yaml:"tags,omitempty"`
+	Slug    string    `yaml:"slug,omitempty"`
+}
+
+type markdownReader struct {
+	dir   string
+	files []string
+	file  string
+}
+
+func newMarkdownReader(dir string) (*markdownReader, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".md") {
+			files = append(files, entry.Name())
+		}
+	}
+	return &markdownReader{dir: dir, files: files}, nil
+}
+
+func (r *markdownReader) Next() (*posts.Post, error) {
+	if len(r.files) == 0 {
+		return nil, io.EOF
+	}
+	r.file, r.files = r.files[0], r.files[1:]
+	content, err := os.ReadFile(filepath.Join(r.dir, r.file))
+	if err != nil {
+		return nil, err
+	}
+	post, err := parseMarkdown(content)
+	if err != nil {
+		return nil, &RecordError{Location: r.Location(), Err: err}
+	}
+	if post.Slug == "" {
+		post.Slug = strings.TrimSuffix(r.file, filepath.Ext(r.file))
+	}
+	return post, nil
+}
+
+func (r *markdownReader) Location() string {
+	return "file " + r.file
+}
+
+// parseMarkdown splits YAML front matter enclosed in "---" lines from content
+func parseMarkdown(content []byte) (*posts.Post, error) {
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	rest, ok := bytes.CutPrefix(content, []byte(frontMatterDelimiter+"\n"))
+	if !ok {
+		return nil, errors.New("front matter is missing")
+	}
+	header, body, ok := bytes.Cut(rest, []byte("\n"+frontMatterDelimiter+"\n"))
+	if !ok {
+		// front matter without content
+		header, ok = bytes.CutSuffix(rest, []byte("\n"+frontMatterDelimiter))
+		if !ok {
+			return nil, errors.New("front matter is not closed")
+		}
+	}
+
+	var fm frontMatter
+	dec := yaml.NewDecoder(bytes.NewReader(header))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fm); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	post := &posts.Post{
+		Title:   fm.Title,
+		Content: strings.TrimPrefix(string(body), "\n"),
+		Tags:    fm.Tags,
+		Slug:    fm.Slug,
+		Created: fm.Created,
+		Updated: fm.Updated,
+	}
+	if fm.ID != "" {
+		id, err := bson.ObjectIDFromHex(fm.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %w", err)
+		}
+		post.ID = id
+	}
+	return post, nil
+}
+
+type markdownWriter struct {
+	dir   string
+	names map[string]struct{}
+}
+
+func newMarkdownWriter(dir string) (*markdownWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &markdownWriter{dir: dir, names: make(map[string]struct{})}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (w *markdownWriter) Write(post *posts.Post) error {
+	// file is named after slug, id makes it unique
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(post.Slug, "-"), "-.")
+	if _, taken := w.names[name]; name == "" || taken {
+		name = strings.TrimPrefix(name+"-"+post.ID.Hex(), "-")
+	}
+	w.names[name] = struct{}{}
+
+	header, err := yaml.Marshal(frontMatter{
+		ID:      post.ID.Hex(),
+		Title:   post.Title,
+		Created: post.Created,
+		Updated: post.Updated,
+		Tags:    post.Tags,
+		Slug:    post.Slug,
+	})
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	b.WriteString(frontMatterDelimiter + "\n")
+	b.Write(header)
+	b.WriteString(frontMatterDelimiter + "\n")
+	b.WriteString(post.Content)
+	return os.WriteFile(filepath.Join(w.dir, name+".md"), b.Bytes(), 0o644)
+}
+
+func (w *markdownWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+type ndjsonReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	return &ndjsonReader{r: bufio.NewReader(r)}
+}
+
+func (r *ndjsonReader) Next() (*posts.Post, error) {
+	for {
+		// lines are not limited in size, as post content may be long
+		line, err := r.r.ReadBytes('\n')
+		if len(line) == 0 && errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		r.line++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var post posts.Post
+		if err := json.Unmarshal(line, &post); err != nil {
+			return nil, &RecordError{Location: r.Location(), Err: err}
+		}
+		return &post, nil
+	}
+}
+
+func (r *ndjsonReader) Location() string {
+	return fmt.Sprintf("line %d", r.line)
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (w *ndjsonWriter) Write(post *posts.Post) error {
+	return w.enc.Encode(post)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,111 @@
+// Package transfer streams posts between mongodb and NDJSON, CSV or Markdown files,
+// and imports legacy content from WordPress and RSS/Atom feeds
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+type Format string
+
+const (
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV has header row, see csvHeader
+	FormatCSV Format = "csv"
+	// FormatMarkdown is a directory of *.md files with YAML front matter
+	FormatMarkdown Format = "markdown"
+	// FormatWXR is WordPress export file, it's import only
+	FormatWXR Format = "wxr"
+	// FormatRSS is RSS 2.0 or Atom feed, it's import only
+	FormatRSS Format = "rss"
+)
+
+// Reader reads posts one by one, Next returns io.EOF after the last post.
+// Malformed post is reported with *RecordError, reading may continue after it
+type Reader interface {
+	Next() (*posts.Post, error)
+	// Location describes the last read post, e.g. "line 3"
+	Location() string
+}
+
+type Writer interface {
+	Write(post *posts.Post) error
+	// Close flushes buffered posts
+	Close() error
+}
+
+// RecordError is an error of a single malformed record
+type RecordError struct {
+	Location string
+	Err      error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Location, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// DetectFormat chooses format by file extension, directory is always FormatMarkdown
+func DetectFormat(path string) (Format, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return FormatMarkdown, nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	case ".rss", ".atom", ".xml":
+		// WXR is read by the same reader
+		return FormatRSS, nil
+	}
+	return "", fmt.Errorf("unable to detect format of %q, specify it explicitly", path)
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatNDJSON, FormatCSV, FormatMarkdown, FormatWXR, FormatRSS:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+// NewReader reads posts from r, markdown posts are read from dir instead
+func NewReader(format Format, r io.Reader, dir string) (Reader, error) {
+	switch format {
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatMarkdown:
+		return newMarkdownReader(dir)
+	case FormatWXR, FormatRSS:
+		return newFeedReader(r), nil
+	}
+	return nil, errors.New("unknown format")
+}
+
+// NewWriter writes posts to w, markdown posts are written to dir instead
+func NewWriter(format Format, w io.Writer, dir string) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatMarkdown:
+		return newMarkdownWriter(dir)
+	case FormatWXR, FormatRSS:
+		return nil, fmt.Errorf("%s format is import only", format)
+	}
+	return nil, errors.New("unknown format")
+}
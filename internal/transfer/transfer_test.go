@@ -0,0 +1,228 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/transfer"
+)
+
+func dummyPosts() []*posts.Post {
+	created := time.Date(2024, 5, 1, 10, 30, 0, 123_000_000, time.UTC)
+	return []*posts.Post{
+		{
+			ID:      bson.NewObjectID(),
+			Title:   "First, post",
+			Content: "Line one\n---\nline \"two\"",
+			Tags:    []string{"go", "mongo"},
+			Slug:    "first-post",
+			Created: created,
+			Updated: created.Add(time.Hour),
+		},
+		{
+			ID:      bson.NewObjectID(),
+			Title:   "Second post",
+			Content: "Content",
+			Created: created,
+			Updated: created,
+		},
+	}
+}
+
+func readAll(t *testing.T, r transfer.Reader) []*posts.Post {
+	t.Helper()
+	var all []*posts.Post
+	for {
+		post, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return all
+		}
+		require.NoError(t, err)
+		all = append(all, post)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []transfer.Format{transfer.FormatNDJSON, transfer.FormatCSV, transfer.FormatMarkdown} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			var buf bytes.Buffer
+			w, err := transfer.NewWriter(format, &buf, dir)
+			require.NoError(t, err)
+			for _, post := range dummyPosts() {
+				require.NoError(t, w.Write(post))
+			}
+			require.NoError(t, w.Close())
+
+			r, err := transfer.NewReader(format, &buf, dir)
+			require.NoError(t, err)
+			read := readAll(t, r)
+			require.Len(t, read, 2)
+
+			expected := dummyPosts()
+			for i := range expected {
+				// order of markdown files depends on names
+				actual := read[i]
+				if format == transfer.FormatMarkdown {
+					actual = read[1-i]
+				}
+				assert.Equal(t, expected[i].Title, actual.Title)
+				assert.Equal(t, expected[i].Content, actual.Content)
+				assert.Equal(t, expected[i].Tags, actual.Tags)
+				assert.True(t, expected[i].Created.Equal(actual.Created))
+				assert.True(t, expected[i].Updated.Equal(actual.Updated))
+			}
+		})
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	// columns in any order, optional columns may be missing
+	in := "content,title,tags\nHello,Greeting,\"a, b\"\n"
+	r, err := transfer.NewReader(transfer.FormatCSV, strings.NewReader(in), "")
+	require.NoError(t, err)
+	read := readAll(t, r)
+	require.Len(t, read, 1)
+	assert.Equal(t, "Greeting", read[0].Title)
+	assert.Equal(t, []string{"a", "b"}, read[0].Tags)
+	assert.True(t, read[0].ID.IsZero())
+
+	_, err = transfer.NewReader(transfer.FormatCSV, strings.NewReader("title\n"), "")
+	assert.ErrorContains(t, err, "content")
+	_, err = transfer.NewReader(transfer.FormatCSV, strings.NewReader("title,content,author\n"), "")
+	assert.ErrorContains(t, err, "author")
+
+	// malformed record
+	r, err = transfer.NewReader(transfer.FormatCSV, strings.NewReader("title,content,created\nA,B,yesterday\n"), "")
+	require.NoError(t, err)
+	_, err = r.Next()
+	var re *transfer.RecordError
+	require.ErrorAs(t, err, &re)
+	assert.Equal(t, "line 2", re.Location)
+
+	// malformed first field
+	for in, location := range map[string]string{
+		"title,content\na\"b,c\n":       "line 2",
+		"title,content\nA,B\n\"abc,c\n": "line 3",
+	} {
+		r, err = transfer.NewReader(transfer.FormatCSV, strings.NewReader(in), "")
+		require.NoError(t, err)
+		_, err = readUntilError(r)
+		require.ErrorAs(t, err, &re, in)
+		assert.Equal(t, location, re.Location, in)
+	}
+}
+
+func readUntilError(r transfer.Reader) ([]*posts.Post, error) {
+	var read []*posts.Post
+	for {
+		post, err := r.Next()
+		if err != nil {
+			return read, err
+		}
+		read = append(read, post)
+	}
+}
+
+func TestMarkdownReader(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello-world.md"), []byte("---\ntitle: Hello\ntags: [news]\ncreated: 2024-01-02T03:04:05Z\n---\n# Hello\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "no-front-matter.md"), []byte("# Hello\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o600))
+
+	r, err := transfer.NewReader(transfer.FormatMarkdown, nil, dir)
+	require.NoError(t, err)
+	post, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", post.Title)
+	assert.Equal(t, "# Hello\n", post.Content)
+	assert.Equal(t, "hello-world", post.Slug) // from file name
+	assert.Equal(t, []string{"news"}, post.Tags)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), post.Created)
+
+	_, err = r.Next()
+	assert.ErrorContains(t, err, "file no-front-matter.md: front matter is missing")
+	_, err = r.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+type fakeSaver struct {
+	batches [][]*posts.Post
+	failed  map[int]error
+}
+
+func (s *fakeSaver) BulkSave(_ context.Context, batch []*posts.Post) (posts.BulkResult, error) {
+	s.batches = append(s.batches, append([]*posts.Post(nil), batch...))
+	return posts.BulkResult{Inserted: len(batch) - len(s.failed), Failed: s.failed}, nil
+}
+
+func TestImporter(t *testing.T) {
+	in := `{"title":"One","content":"1"}
+{"title":"","content":"invalid"}
+not json
+
+{"title":"Two","content":"2","createdAt":"2024-01-01T00:00:00Z"}
+{"title":"Three","content":"3"}
+`
+	saver := &fakeSaver{}
+	importer := transfer.NewImporter(saver)
+	importer.BatchSize = 2
+	importer.Validate = func(post *posts.Post) error {
+		if post.Title == "" {
+			return errors.New("title is required")
+		}
+		return nil
+	}
+
+	report, err := importer.Import(context.Background(), mustReader(t, in))
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Read)
+	assert.Equal(t, 3, report.Inserted)
+	assert.Equal(t, 2, report.Invalid)
+	require.Len(t, report.Errors, 2)
+	assert.Equal(t, "line 2: title is required", report.Errors[0].Error())
+	assert.Contains(t, report.Errors[1].Error(), "line 3")
+
+	require.Len(t, saver.batches, 2)
+	assert.Len(t, saver.batches[0], 2)
+	assert.Len(t, saver.batches[1], 1)
+	// original timestamps are kept, missing ones are set
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), saver.batches[0][1].Created)
+	assert.False(t, saver.batches[0][0].Created.IsZero())
+	assert.Equal(t, saver.batches[0][0].Created, saver.batches[0][0].Updated)
+
+	// failed writes are reported with location
+	saver = &fakeSaver{failed: map[int]error{0: posts.ErrConflict}}
+	report, err = transfer.NewImporter(saver).Import(context.Background(), mustReader(t, in))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Failed)
+	assert.Contains(t, report.Errors[len(report.Errors)-1].Error(), "line 1")
+}
+
+func TestImporter_DryRun(t *testing.T) {
+	importer := transfer.NewImporter(nil)
+	importer.DryRun = true
+	report, err := importer.Import(context.Background(), mustReader(t, `{"title":"One","content":"1"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Read)
+	assert.Equal(t, "dry run: read 1, valid 1, invalid 0", report.String())
+}
+
+func mustReader(t *testing.T, in string) transfer.Reader {
+	t.Helper()
+	r, err := transfer.NewReader(transfer.FormatNDJSON, strings.NewReader(in), "")
+	require.NoError(t, err)
+	return r
+}
@@ -0,0 +1,9 @@
+package users
+
+import "errors"
+
+var (
+	ErrNotFound   = errors.New("user not found")
+	ErrEmailTaken = errors.New("email is already taken")
+	ErrInvalid    = errors.New("user is invalid")
+)
@@ -0,0 +1,74 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel"
+
+	"github.com/mineroot/news/internal/tracing"
+)
+
+var tracer = otel.Tracer("github.com/mineroot/news/internal/users")
+
+type Repository struct {
+	collection *mongo.Collection
+}
+
+func NewRepository(collection *mongo.Collection) *Repository {
+	return &Repository{collection: collection}
+}
+
+// Create returns ErrEmailTaken if user with such email exists
+func (r *Repository) Create(ctx context.Context, user *User) (_ *User, err error) {
+	ctx, span := tracer.Start(ctx, "users.Create")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.ID = result.InsertedID.(bson.ObjectID)
+	return user, nil
+}
+
+// FindByEmail returns ErrNotFound if there is no user with such email
+func (r *Repository) FindByEmail(ctx context.Context, email string) (_ *User, err error) {
+	ctx, span := tracer.Start(ctx, "users.FindByEmail")
+	defer func() { tracing.End(span, err) }()
+
+	var user User
+	err = r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetRole returns ErrNotFound if there is no user with such email
+func (r *Repository) SetRole(ctx context.Context, email string, role Role) (_ *User, err error) {
+	ctx, span := tracer.Start(ctx, "users.SetRole")
+	defer func() { tracing.End(span, err) }()
+
+	update := bson.M{"$set": bson.M{"role": role, "updatedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var user User
+	err = r.collection.FindOneAndUpdate(ctx, bson.M{"email": email}, update, opts).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,87 @@
+package users
+
+import (
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Role string
+
+const (
+	// RoleAdmin manages posts and users
+	RoleAdmin Role = "admin"
+	// RoleEditor manages posts
+	RoleEditor Role = "editor"
+)
+
+var Roles = []Role{RoleAdmin, RoleEditor}
+
+// MinPasswordLength is a minimal password length in bytes, bcrypt ignores everything after 72 bytes
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+type User struct {
+	ID           bson.ObjectID `bson:"_id,omitempty"`
+	Email        string        `bson:"email"`
+	PasswordHash []byte        `bson:"passwordHash"`
+	Role         Role          `bson:"role"`
+	Created      time.Time     `bson:"createdAt"`
+	Updated      time.Time     `bson:"updatedAt"`
+}
+
+// New creates a user with hashed password, email is normalized to lower case
+func New(email, password string, role Role) (*User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user := &User{Email: email, Role: role, Created: now, Updated: now}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *User) SetPassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return fmt.Errorf("%w: password must be %d-%d bytes long", ErrInvalid, MinPasswordLength, MaxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
+// CheckPassword reports whether password matches the stored hash
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)) == nil
+}
+
+func ParseRole(s string) (Role, error) {
+	if !slices.Contains(Roles, Role(s)) {
+		return "", fmt.Errorf("%w: unknown role %q", ErrInvalid, s)
+	}
+	return Role(s), nil
+}
+
+func NormalizeEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" {
+		return "", fmt.Errorf("%w: invalid email %q", ErrInvalid, email)
+	}
+	return strings.ToLower(addr.Address), nil
+}
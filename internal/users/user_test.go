@@ -0,0 +1,38 @@
+package users_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/users"
+)
+
+func TestNew(t *testing.T) {
+	user, err := users.New(" Admin@Example.com ", "s3cret-password", users.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, "admin@example.com", user.Email)
+	assert.Equal(t, users.RoleAdmin, user.Role)
+	assert.NotContains(t, string(user.PasswordHash), "s3cret-password")
+	assert.True(t, user.CheckPassword("s3cret-password"))
+	assert.False(t, user.CheckPassword("wrong-password"))
+
+	_, err = users.New("not an email", "s3cret-password", users.RoleAdmin)
+	assert.ErrorIs(t, err, users.ErrInvalid)
+	_, err = users.New("Admin <admin@example.com>", "s3cret-password", users.RoleAdmin)
+	assert.ErrorIs(t, err, users.ErrInvalid)
+	_, err = users.New("admin@example.com", "short", users.RoleAdmin)
+	assert.ErrorIs(t, err, users.ErrInvalid)
+	_, err = users.New("admin@example.com", "s3cret-password", "root")
+	assert.ErrorIs(t, err, users.ErrInvalid)
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := users.ParseRole("editor")
+	require.NoError(t, err)
+	assert.Equal(t, users.RoleEditor, role)
+
+	_, err = users.ParseRole("Editor")
+	assert.ErrorIs(t, err, users.ErrInvalid)
+}
@@ -0,0 +1,77 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/postcache"
+	"github.com/mineroot/news/internal/posts"
+)
+
+// Queue takes events for delivery
+type Queue interface {
+	Enqueue(ctx context.Context, event string, body []byte, now time.Time) (int, error)
+}
+
+// Emitter queues events of posts changed through it, reads are passed to the repository
+type Emitter struct {
+	postcache.Repository
+	queue Queue
+}
+
+func NewEmitter(repo postcache.Repository, queue Queue) *Emitter {
+	return &Emitter{Repository: repo, queue: queue}
+}
+
+func (e *Emitter) Create(ctx context.Context, post *posts.Post) (*posts.Post, error) {
+	created, err := e.Repository.Create(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	e.emit(ctx, PostCreated, created)
+	return created, nil
+}
+
+func (e *Emitter) UpdateById(ctx context.Context, id bson.ObjectID, title, content string) (*posts.Post, error) {
+	updated, err := e.Repository.UpdateById(ctx, id, title, content)
+	if err != nil {
+		return nil, err
+	}
+	e.emit(ctx, PostUpdated, updated)
+	return updated, nil
+}
+
+func (e *Emitter) DeleteById(ctx context.Context, id bson.ObjectID) (*posts.Post, error) {
+	deleted, err := e.Repository.DeleteById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e.emit(ctx, PostDeleted, deleted)
+	return deleted, nil
+}
+
+// RestoreById emits PostCreated, for subscribers restored post is a new one
+func (e *Emitter) RestoreById(ctx context.Context, id bson.ObjectID) (*posts.Post, error) {
+	restored, err := e.Repository.RestoreById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e.emit(ctx, PostCreated, restored)
+	return restored, nil
+}
+
+// emit never fails the change, post is already saved, so the event is only logged as lost
+func (e *Emitter) emit(ctx context.Context, event string, post *posts.Post) {
+	now := time.Now()
+	body, err := json.Marshal(payload{ID: bson.NewObjectID(), Event: event, Created: now, Post: post})
+	if err == nil {
+		_, err = e.queue.Enqueue(ctx, event, body, now)
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("event", event).Str("post", post.ID.Hex()).Msg("unable to queue webhook event")
+	}
+}
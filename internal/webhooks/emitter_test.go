@@ -0,0 +1,113 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/paging"
+	"github.com/mineroot/news/internal/posts"
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+type fakeRepo struct {
+	post *posts.Post
+	err  error
+}
+
+func (r *fakeRepo) FindAllByQueryWithPagination(context.Context, *paging.Paginator, string) ([]*posts.Post, paging.Total, error) {
+	return []*posts.Post{r.post}, paging.Total{Items: 1}, r.err
+}
+
+func (r *fakeRepo) FindById(context.Context, bson.ObjectID) (*posts.Post, error) {
+	return r.post, r.err
+}
+
+func (r *fakeRepo) Create(_ context.Context, post *posts.Post) (*posts.Post, error) {
+	return post, r.err
+}
+
+func (r *fakeRepo) UpdateById(context.Context, bson.ObjectID, string, string) (*posts.Post, error) {
+	return r.post, r.err
+}
+
+func (r *fakeRepo) DeleteById(context.Context, bson.ObjectID) (*posts.Post, error) {
+	return r.post, r.err
+}
+
+func (r *fakeRepo) RestoreById(context.Context, bson.ObjectID) (*posts.Post, error) {
+	return r.post, r.err
+}
+
+type queued struct {
+	event string
+	body  []byte
+}
+
+type fakeQueue struct {
+	queued []queued
+	err    error
+}
+
+func (q *fakeQueue) Enqueue(_ context.Context, event string, body []byte, _ time.Time) (int, error) {
+	q.queued = append(q.queued, queued{event: event, body: body})
+	return 1, q.err
+}
+
+func TestEmitter(t *testing.T) {
+	post := &posts.Post{ID: bson.NewObjectID(), Title: "Title", Content: "Content"}
+	queue := &fakeQueue{}
+	emitter := webhooks.NewEmitter(&fakeRepo{post: post}, queue)
+	ctx := context.Background()
+
+	_, err := emitter.Create(ctx, post)
+	require.NoError(t, err)
+	_, err = emitter.UpdateById(ctx, post.ID, "Title", "Content")
+	require.NoError(t, err)
+	_, err = emitter.DeleteById(ctx, post.ID)
+	require.NoError(t, err)
+	_, err = emitter.RestoreById(ctx, post.ID)
+	require.NoError(t, err)
+	// reads are not events
+	_, err = emitter.FindById(ctx, post.ID)
+	require.NoError(t, err)
+
+	require.Len(t, queue.queued, 4)
+	events := []string{webhooks.PostCreated, webhooks.PostUpdated, webhooks.PostDeleted, webhooks.PostCreated}
+	for i, q := range queue.queued {
+		assert.Equal(t, events[i], q.event)
+		var payload struct {
+			ID    string     `json:"id"`
+			Event string     `json:"event"`
+			Post  posts.Post `json:"post"`
+		}
+		require.NoError(t, json.Unmarshal(q.body, &payload))
+		assert.NotEmpty(t, payload.ID)
+		assert.Equal(t, events[i], payload.Event)
+		assert.Equal(t, post.ID, payload.Post.ID)
+		assert.Equal(t, "Title", payload.Post.Title)
+	}
+}
+
+func TestEmitter_Errors(t *testing.T) {
+	post := &posts.Post{ID: bson.NewObjectID()}
+	ctx := context.Background()
+
+	// failed change is not an event
+	queue := &fakeQueue{}
+	_, err := webhooks.NewEmitter(&fakeRepo{err: posts.ErrNotFound}, queue).DeleteById(ctx, post.ID)
+	assert.ErrorIs(t, err, posts.ErrNotFound)
+	assert.Empty(t, queue.queued)
+
+	// change is saved, so it succeeds when event is lost
+	queue = &fakeQueue{err: errors.New("unavailable")}
+	updated, err := webhooks.NewEmitter(&fakeRepo{post: post}, queue).UpdateById(ctx, post.ID, "Title", "Content")
+	assert.NoError(t, err)
+	assert.Equal(t, post, updated)
+}
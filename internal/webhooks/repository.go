@@ -0,0 +1,186 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel"
+
+	"github.com/mineroot/news/internal/tracing"
+)
+
+var tracer = otel.Tracer("github.com/mineroot/news/internal/webhooks")
+
+// Repository stores subscriptions and deliveries, deliveries collection is the queue of Worker
+type Repository struct {
+	subscriptions *mongo.Collection
+	deliveries    *mongo.Collection
+}
+
+func NewRepository(subscriptions, deliveries *mongo.Collection) *Repository {
+	return &Repository{subscriptions: subscriptions, deliveries: deliveries}
+}
+
+func (r *Repository) CreateSubscription(ctx context.Context, sub *Subscription) (*Subscription, error) {
+	result, err := r.subscriptions.InsertOne(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	sub.ID = result.InsertedID.(bson.ObjectID)
+	return sub, nil
+}
+
+func (r *Repository) Subscriptions(ctx context.Context) ([]*Subscription, error) {
+	cursor, err := r.subscriptions.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	subs := []*Subscription{}
+	if err := cursor.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// DeleteSubscription returns ErrNotFound if there is no subscription with such id, its pending deliveries are still sent
+func (r *Repository) DeleteSubscription(ctx context.Context, id bson.ObjectID) error {
+	result, err := r.subscriptions.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Enqueue adds delivery of event to every subscription of it, it returns number of deliveries
+func (r *Repository) Enqueue(ctx context.Context, event string, body []byte, now time.Time) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "webhooks.Enqueue")
+	defer func() { tracing.End(span, err) }()
+
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "events", Value: event}},
+		bson.D{{Key: "events", Value: bson.D{{Key: "$in", Value: bson.A{nil, bson.A{}}}}}},
+	}}}
+	var subs []*Subscription
+	cursor, err := r.subscriptions.Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "url", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+	if err := cursor.All(ctx, &subs); err != nil {
+		return 0, err
+	}
+	if len(subs) == 0 {
+		return 0, nil
+	}
+
+	deliveries := make([]*Delivery, 0, len(subs))
+	for _, sub := range subs {
+		deliveries = append(deliveries, &Delivery{
+			Subscription: sub.ID,
+			URL:          sub.URL,
+			Event:        event,
+			Payload:      body,
+			Status:       StatusPending,
+			NextAttempt:  now,
+			Created:      now,
+		})
+	}
+	if _, err := r.deliveries.InsertMany(ctx, deliveries); err != nil {
+		return 0, err
+	}
+	return len(deliveries), nil
+}
+
+// Claim takes the earliest due delivery and hides it from other workers until lease ends,
+// so it's retried if worker dies while sending it. It returns nil if there are no due deliveries
+func (r *Repository) Claim(ctx context.Context, now time.Time, lease time.Duration) (*Delivery, error) {
+	filter := bson.D{
+		{Key: "status", Value: StatusPending},
+		{Key: "nextAttemptAt", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "nextAttemptAt", Value: now.Add(lease)}}}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+	var delivery Delivery
+	err := r.deliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Save stores result of delivery attempt
+func (r *Repository) Save(ctx context.Context, delivery *Delivery) error {
+	_, err := r.deliveries.ReplaceOne(ctx, bson.D{{Key: "_id", Value: delivery.ID}}, delivery)
+	return err
+}
+
+// FindDeliveries returns the latest deliveries with status, empty status matches all of them
+func (r *Repository) FindDeliveries(ctx context.Context, status Status, limit int) (_ []*Delivery, err error) {
+	ctx, span := tracer.Start(ctx, "webhooks.FindDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	filter := bson.D{}
+	if status != "" {
+		filter = bson.D{{Key: "status", Value: status}}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.D{{Key: "payload", Value: 0}})
+	cursor, err := r.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []*Delivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues dead delivery again with all attempts, it returns ErrNotFound if there is no dead delivery with such id
+func (r *Repository) Redeliver(ctx context.Context, id bson.ObjectID) (_ *Delivery, err error) {
+	ctx, span := tracer.Start(ctx, "webhooks.Redeliver")
+	defer func() { tracing.End(span, err) }()
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "status", Value: StatusDead}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "status", Value: StatusPending},
+		{Key: "attempts", Value: 0},
+		{Key: "nextAttemptAt", Value: time.Now()},
+	}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.D{{Key: "payload", Value: 0}})
+	var delivery Delivery
+	err = r.deliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// FindSubscription returns ErrNotFound if there is no subscription with such id
+func (r *Repository) FindSubscription(ctx context.Context, id bson.ObjectID) (*Subscription, error) {
+	var sub Subscription
+	err := r.subscriptions.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&sub)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
@@ -0,0 +1,87 @@
+package webhooks_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/db"
+	"github.com/mineroot/news/internal/dbtest"
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	mongoClient := dbtest.MigratedMongo(t)
+	repo := webhooks.NewRepository(
+		db.GetWebhooksCollection(mongoClient, db.ClientConfig{}),
+		db.GetWebhookDeliveriesCollection(mongoClient, db.ClientConfig{}),
+	)
+
+	all, err := webhooks.NewSubscription("https://example.com/all", "secret", nil)
+	require.NoError(t, err)
+	_, err = repo.CreateSubscription(ctx, all)
+	require.NoError(t, err)
+	deleted, err := webhooks.NewSubscription("https://example.com/deleted", "secret", []string{webhooks.PostDeleted})
+	require.NoError(t, err)
+	_, err = repo.CreateSubscription(ctx, deleted)
+	require.NoError(t, err)
+	subs, err := repo.Subscriptions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, subs, 2)
+
+	// only subscriptions of event get deliveries
+	now := time.Now().Truncate(time.Millisecond)
+	count, err := repo.Enqueue(ctx, webhooks.PostCreated, []byte(`{}`), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = repo.Enqueue(ctx, webhooks.PostDeleted, []byte(`{}`), now.Add(time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// claimed delivery is hidden until lease ends
+	claimed, err := repo.Claim(ctx, now, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, webhooks.PostCreated, claimed.Event)
+	assert.Equal(t, []byte(`{}`), claimed.Payload)
+	claimedAgain, err := repo.Claim(ctx, now, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, claimedAgain, "other deliveries are not due yet")
+	claimedAgain, err = repo.Claim(ctx, now.Add(time.Minute), time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimedAgain)
+	assert.NotEqual(t, claimed.ID, claimedAgain.ID)
+
+	// dead delivery is in dead letters until redelivered
+	claimed.Status = webhooks.StatusDead
+	claimed.Attempts = 10
+	claimed.LastError = "unexpected status 500 Internal Server Error"
+	require.NoError(t, repo.Save(ctx, claimed))
+	dead, err := repo.FindDeliveries(ctx, webhooks.StatusDead, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, claimed.ID, dead[0].ID)
+	assert.Nil(t, dead[0].Payload, "log has no payloads")
+	log, err := repo.FindDeliveries(ctx, "", 10)
+	require.NoError(t, err)
+	assert.Len(t, log, 3)
+
+	redelivered, err := repo.Redeliver(ctx, claimed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhooks.StatusPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+	_, err = repo.Redeliver(ctx, claimed.ID)
+	assert.ErrorIs(t, err, webhooks.ErrNotFound, "only dead deliveries are redelivered")
+
+	require.NoError(t, repo.DeleteSubscription(ctx, deleted.ID))
+	assert.ErrorIs(t, repo.DeleteSubscription(ctx, deleted.ID), webhooks.ErrNotFound)
+	_, err = repo.FindSubscription(ctx, deleted.ID)
+	assert.ErrorIs(t, err, webhooks.ErrNotFound)
+	_, err = repo.FindSubscription(ctx, bson.NewObjectID())
+	assert.ErrorIs(t, err, webhooks.ErrNotFound)
+}
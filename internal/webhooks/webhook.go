@@ -0,0 +1,113 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/posts"
+)
+
+// events sent to subscribers
+const (
+	PostCreated = "post.created"
+	PostUpdated = "post.updated"
+	PostDeleted = "post.deleted"
+)
+
+var Events = []string{PostCreated, PostUpdated, PostDeleted}
+
+var (
+	ErrNotFound = errors.New("webhook not found")
+	ErrInvalid  = errors.New("webhook is invalid")
+)
+
+// headers of delivery request
+const (
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderID is id of delivery, it's the same for all attempts, so receivers can skip duplicates
+	HeaderID        = "X-Webhook-Id"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Subscription sends events to URL, every request is signed with Secret
+type Subscription struct {
+	ID     bson.ObjectID `bson:"_id,omitempty"`
+	URL    string        `bson:"url"`
+	Secret string        `bson:"secret"`
+	// Events filters sent events, empty means all events
+	Events  []string  `bson:"events"`
+	Created time.Time `bson:"createdAt"`
+}
+
+// NewSubscription validates URL and events
+func NewSubscription(rawURL, secret string, events []string) (*Subscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be absolute http(s) url", ErrInvalid)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("%w: secret is required", ErrInvalid)
+	}
+	for _, event := range events {
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalid, event)
+		}
+	}
+	return &Subscription{URL: rawURL, Secret: secret, Events: events, Created: time.Now()}, nil
+}
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	// StatusDead is a delivery which is not retried anymore, it's kept until redelivered
+	StatusDead Status = "dead"
+)
+
+// Delivery is an event sent to a subscription, it's retried until delivered or dead
+type Delivery struct {
+	ID           bson.ObjectID `bson:"_id,omitempty"`
+	Subscription bson.ObjectID `bson:"subscription"`
+	URL          string        `bson:"url"`
+	Event        string        `bson:"event"`
+	Payload      []byte        `bson:"payload"`
+	Status       Status        `bson:"status"`
+	Attempts     int           `bson:"attempts"`
+	// NextAttempt is also pushed forward while delivery is being sent, so other workers skip it
+	NextAttempt time.Time `bson:"nextAttemptAt"`
+	// LastStatus is http status code of the last attempt, 0 if there was no response
+	LastStatus int       `bson:"lastStatus,omitempty"`
+	LastError  string    `bson:"lastError,omitempty"`
+	Created    time.Time `bson:"createdAt"`
+	Delivered  time.Time `bson:"deliveredAt,omitempty"`
+	// Expires removes delivered ones from the log
+	Expires time.Time `bson:"expiresAt,omitempty"`
+}
+
+// payload is JSON body of delivery request
+type payload struct {
+	ID      bson.ObjectID `json:"id"`
+	Event   string        `json:"event"`
+	Created time.Time     `json:"createdAt"`
+	Post    *posts.Post   `json:"post"`
+}
+
+// Sign is value of HeaderSignature: "t=TIMESTAMP,v1=HMAC", where HMAC is hex encoded HMAC-SHA256 of "TIMESTAMP.BODY".
+// Timestamp is signed, so receivers can reject replayed requests
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,33 @@
+package webhooks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+func TestNewSubscription(t *testing.T) {
+	sub, err := webhooks.NewSubscription("https://example.com/hook", "secret", []string{webhooks.PostCreated})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook", sub.URL)
+	assert.Equal(t, []string{webhooks.PostCreated}, sub.Events)
+
+	for _, url := range []string{"", "example.com/hook", "ftp://example.com", "https://"} {
+		_, err = webhooks.NewSubscription(url, "secret", nil)
+		assert.ErrorIs(t, err, webhooks.ErrInvalid, url)
+	}
+	_, err = webhooks.NewSubscription("https://example.com/hook", "", nil)
+	assert.ErrorIs(t, err, webhooks.ErrInvalid)
+	_, err = webhooks.NewSubscription("https://example.com/hook", "secret", []string{"post.published"})
+	assert.ErrorIs(t, err, webhooks.ErrInvalid)
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1746100800.{}' | openssl dgst -sha256 -hmac secret
+	signature := webhooks.Sign("secret", time.Unix(1746100800, 0), []byte("{}"))
+	assert.Equal(t, "t=1746100800,v1=c899a7b5b0c796a5d5d1efcf7ec856948c1f057f3721c853a883f8f0e1ccc98c", signature)
+}
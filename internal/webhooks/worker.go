@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/rs/zerolog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/buildinfo"
+	"github.com/mineroot/news/internal/metrics"
+)
+
+// maxErrorLength limits error of attempt stored in delivery log
+const maxErrorLength = 200
+
+// Store is the queue of deliveries
+type Store interface {
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (*Delivery, error)
+	Save(ctx context.Context, delivery *Delivery) error
+	FindSubscription(ctx context.Context, id bson.ObjectID) (*Subscription, error)
+}
+
+// Worker sends queued deliveries, every replica runs one, deliveries are claimed, so each is sent by a single replica
+type Worker struct {
+	store  Store
+	client *http.Client
+	now    func() time.Time
+
+	// Concurrency is number of deliveries sent at once
+	Concurrency int
+	// PollInterval is a pause after the queue is empty
+	PollInterval time.Duration
+	// Lease is how long claimed delivery is hidden from other workers, it must be longer than Timeout
+	Lease time.Duration
+	// MaxAttempts is number of attempts before delivery is dead
+	MaxAttempts int
+	// RetryInterval is a pause before the first retry, the next pauses grow exponentially up to MaxRetryInterval
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	// Retention is how long delivered ones are kept in the log
+	Retention time.Duration
+}
+
+// NewWorker sends deliveries with timeout, redirects are not followed
+func NewWorker(store Store, timeout time.Duration) *Worker {
+	return &Worker{
+		store: store,
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now:              time.Now,
+		Concurrency:      4,
+		PollInterval:     time.Second,
+		Lease:            timeout + time.Minute,
+		MaxAttempts:      10,
+		RetryInterval:    10 * time.Second,
+		MaxRetryInterval: time.Hour,
+		Retention:        7 * 24 * time.Hour,
+	}
+}
+
+// SetClock replaces time.Now, it's used by tests
+func (w *Worker) SetClock(now func() time.Time) {
+	w.now = now
+}
+
+// Run sends deliveries until ctx is cancelled, a delivery is claimed only when there is a free sender
+func (w *Worker) Run(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+	senders := make(chan struct{}, w.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case senders <- struct{}{}:
+		}
+		delivery, err := w.store.Claim(ctx, w.now(), w.Lease)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			logger.Warn().Err(err).Msg("unable to claim webhook delivery")
+		}
+		if delivery == nil {
+			<-senders
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(w.PollInterval):
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-senders }()
+			w.Deliver(ctx, delivery)
+		}()
+	}
+}
+
+// Deliver makes an attempt and saves its result, delivery is retried later if it fails
+func (w *Worker) Deliver(ctx context.Context, delivery *Delivery) {
+	logger := zerolog.Ctx(ctx).With().Str("delivery", delivery.ID.Hex()).Str("event", delivery.Event).Logger()
+
+	sub, err := w.store.FindSubscription(ctx, delivery.Subscription)
+	if errors.Is(err, ErrNotFound) {
+		delivery.Status = StatusDead
+		delivery.LastError = "subscription is deleted"
+		metrics.WebhookDeliveries.WithLabelValues(delivery.Event, "dead").Inc()
+		w.save(ctx, &logger, delivery)
+		return
+	}
+	if err != nil {
+		// delivery is claimed again after lease
+		logger.Warn().Err(err).Msg("unable to find webhook subscription")
+		return
+	}
+
+	now := w.now()
+	delivery.URL = sub.URL
+	status, err := w.send(ctx, sub, delivery, now)
+	if ctx.Err() != nil {
+		// interrupted by shutdown, it's not an attempt
+		return
+	}
+	delivery.Attempts++
+	delivery.LastStatus = status
+	delivery.LastError = ""
+	result := "delivered"
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+		delivery.Delivered = now
+		delivery.Expires = now.Add(w.Retention)
+	case delivery.Attempts >= w.MaxAttempts:
+		result = "dead"
+		delivery.Status = StatusDead
+		delivery.LastError = truncate(err.Error())
+		logger.Warn().Err(err).Int("attempts", delivery.Attempts).Msg("webhook delivery is dead")
+	default:
+		result = "retry"
+		delivery.LastError = truncate(err.Error())
+		delivery.NextAttempt = now.Add(w.retryDelay(delivery.Attempts))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(delivery.Event, result).Inc()
+	w.save(ctx, &logger, delivery)
+}
+
+// send posts signed payload, it returns response status code
+func (w *Worker) send(ctx context.Context, sub *Subscription, delivery *Delivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "news-webhooks/"+buildinfo.Get().Version)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderID, delivery.ID.Hex())
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, now, delivery.Payload))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// drain body, so connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+func (w *Worker) save(ctx context.Context, logger *zerolog.Logger, delivery *Delivery) {
+	if err := w.store.Save(context.WithoutCancel(ctx), delivery); err != nil {
+		logger.Error().Err(err).Msg("unable to save webhook delivery")
+	}
+}
+
+// retryDelay is exponential backoff after attempts
+func (w *Worker) retryDelay(attempts int) time.Duration {
+	b := backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(w.RetryInterval),
+		backoff.WithMaxInterval(w.MaxRetryInterval),
+		backoff.WithMaxElapsedTime(0),
+	)
+	var delay time.Duration
+	for range attempts {
+		delay = b.NextBackOff()
+	}
+	return delay
+}
+
+func truncate(s string) string {
+	if len(s) <= maxErrorLength {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxErrorLength], "") + "…"
+}
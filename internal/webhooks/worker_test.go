@@ -0,0 +1,250 @@
+package webhooks_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/mineroot/news/internal/webhooks"
+)
+
+// fakeStore is in-memory Store
+type fakeStore struct {
+	mu         sync.Mutex
+	subs       map[bson.ObjectID]*webhooks.Subscription
+	deliveries []*webhooks.Delivery
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{subs: make(map[bson.ObjectID]*webhooks.Subscription)}
+}
+
+func (s *fakeStore) subscribe(url, secret string) *webhooks.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := &webhooks.Subscription{ID: bson.NewObjectID(), URL: url, Secret: secret}
+	s.subs[sub.ID] = sub
+	return sub
+}
+
+func (s *fakeStore) enqueue(sub *webhooks.Subscription, event string, body []byte, now time.Time) *webhooks.Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery := &webhooks.Delivery{
+		ID:           bson.NewObjectID(),
+		Subscription: sub.ID,
+		URL:          sub.URL,
+		Event:        event,
+		Payload:      body,
+		Status:       webhooks.StatusPending,
+		NextAttempt:  now,
+		Created:      now,
+	}
+	s.deliveries = append(s.deliveries, delivery)
+	return delivery
+}
+
+func (s *fakeStore) get(id bson.ObjectID) webhooks.Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			return *d
+		}
+	}
+	panic("no delivery")
+}
+
+func (s *fakeStore) Claim(_ context.Context, now time.Time, lease time.Duration) (*webhooks.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.deliveries {
+		if d.Status == webhooks.StatusPending && !d.NextAttempt.After(now) {
+			d.NextAttempt = now.Add(lease)
+			claimed := *d
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) Save(_ context.Context, delivery *webhooks.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, d := range s.deliveries {
+		if d.ID == delivery.ID {
+			saved := *delivery
+			s.deliveries[i] = &saved
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) FindSubscription(_ context.Context, id bson.ObjectID) (*webhooks.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.subs[id]
+	if !ok {
+		return nil, webhooks.ErrNotFound
+	}
+	return sub, nil
+}
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver responds with statuses in order, the last one is repeated
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan request) {
+	requests := make(chan request, 10)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		mu.Unlock()
+		requests <- request{header: r.Header, body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestWorker_Deliver(t *testing.T) {
+	server, requests := receiver(t, http.StatusNoContent)
+	store := newFakeStore()
+	sub := store.subscribe(server.URL, "secret")
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"post.created"}`)
+	delivery := store.enqueue(sub, webhooks.PostCreated, body, now)
+	worker := webhooks.NewWorker(store, time.Second)
+	worker.SetClock(func() time.Time { return now })
+
+	worker.Deliver(context.Background(), delivery)
+
+	req := <-requests
+	assert.Equal(t, body, req.body)
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.Equal(t, webhooks.PostCreated, req.header.Get(webhooks.HeaderEvent))
+	assert.Equal(t, delivery.ID.Hex(), req.header.Get(webhooks.HeaderID))
+	assert.Equal(t, webhooks.Sign("secret", now, body), req.header.Get(webhooks.HeaderSignature))
+
+	saved := store.get(delivery.ID)
+	assert.Equal(t, webhooks.StatusDelivered, saved.Status)
+	assert.Equal(t, 1, saved.Attempts)
+	assert.Equal(t, http.StatusNoContent, saved.LastStatus)
+	assert.Equal(t, now, saved.Delivered)
+	assert.Equal(t, now.Add(worker.Retention), saved.Expires)
+}
+
+func TestWorker_DeliverRetries(t *testing.T) {
+	server, requests := receiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	store := newFakeStore()
+	sub := store.subscribe(server.URL, "secret")
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	delivery := store.enqueue(sub, webhooks.PostUpdated, []byte(`{}`), now)
+	worker := webhooks.NewWorker(store, time.Second)
+	worker.SetClock(func() time.Time { return now })
+	worker.MaxAttempts = 3
+
+	var delays []time.Duration
+	for range worker.MaxAttempts {
+		d := store.get(delivery.ID)
+		worker.Deliver(context.Background(), &d)
+		<-requests
+		saved := store.get(delivery.ID)
+		delays = append(delays, saved.NextAttempt.Sub(now))
+	}
+
+	saved := store.get(delivery.ID)
+	assert.Equal(t, webhooks.StatusDead, saved.Status)
+	assert.Equal(t, 3, saved.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, saved.LastStatus)
+	assert.Equal(t, "unexpected status 503 Service Unavailable", saved.LastError)
+	assert.True(t, saved.Expires.IsZero(), "dead delivery is kept")
+	// exponential backoff randomized by ±50%
+	assert.InDelta(t, worker.RetryInterval, delays[0], float64(worker.RetryInterval)/2)
+	assert.InDelta(t, worker.RetryInterval*3/2, delays[1], float64(worker.RetryInterval*3/2)/2)
+}
+
+func TestWorker_DeliverUnreachable(t *testing.T) {
+	server, _ := receiver(t, http.StatusOK)
+	server.Close()
+	store := newFakeStore()
+	sub := store.subscribe(server.URL, "secret")
+	delivery := store.enqueue(sub, webhooks.PostDeleted, []byte(`{}`), time.Now())
+	worker := webhooks.NewWorker(store, time.Second)
+
+	worker.Deliver(context.Background(), delivery)
+
+	saved := store.get(delivery.ID)
+	assert.Equal(t, webhooks.StatusPending, saved.Status)
+	assert.Equal(t, 1, saved.Attempts)
+	assert.Zero(t, saved.LastStatus)
+	assert.Contains(t, saved.LastError, "connection refused")
+	assert.True(t, saved.NextAttempt.After(time.Now()))
+}
+
+func TestWorker_DeliverRedirectIsNotFollowed(t *testing.T) {
+	target, requests := receiver(t, http.StatusOK)
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer server.Close()
+	store := newFakeStore()
+	sub := store.subscribe(server.URL, "secret")
+	delivery := store.enqueue(sub, webhooks.PostCreated, []byte(`{}`), time.Now())
+
+	webhooks.NewWorker(store, time.Second).Deliver(context.Background(), delivery)
+
+	assert.Empty(t, requests)
+	assert.Equal(t, http.StatusFound, store.get(delivery.ID).LastStatus)
+}
+
+func TestWorker_DeliverDeletedSubscription(t *testing.T) {
+	store := newFakeStore()
+	sub := &webhooks.Subscription{ID: bson.NewObjectID(), URL: "http://localhost"}
+	delivery := store.enqueue(sub, webhooks.PostCreated, []byte(`{}`), time.Now())
+
+	webhooks.NewWorker(store, time.Second).Deliver(context.Background(), delivery)
+
+	saved := store.get(delivery.ID)
+	assert.Equal(t, webhooks.StatusDead, saved.Status)
+	assert.Equal(t, "subscription is deleted", saved.LastError)
+}
+
+func TestWorker_Run(t *testing.T) {
+	server, requests := receiver(t, http.StatusInternalServerError, http.StatusOK)
+	store := newFakeStore()
+	sub := store.subscribe(server.URL, "secret")
+	delivery := store.enqueue(sub, webhooks.PostCreated, []byte(`{}`), time.Now())
+	worker := webhooks.NewWorker(store, time.Second)
+	worker.PollInterval = time.Millisecond
+	worker.RetryInterval = time.Millisecond
+	worker.MaxRetryInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- worker.Run(ctx) }()
+
+	<-requests
+	<-requests
+	require.Eventually(t, func() bool {
+		return store.get(delivery.ID).Status == webhooks.StatusDelivered
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 2, store.get(delivery.ID).Attempts)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Empty(t, requests, "delivered once")
+}
@@ -0,0 +1,26 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/mineroot/news/internal/middlewares"
+)
+
+type csrfKey struct{}
+
+// WithCSRFToken makes token available to forms and htmx requests rendered with ctx
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfKey{}, token)
+}
+
+func csrfToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfKey{}).(string)
+	return token
+}
+
+// csrfHeaders is hx-headers value, htmx sends it with every request made from the page
+func csrfHeaders(ctx context.Context) string {
+	headers, _ := json.Marshal(map[string]string{middlewares.CSRFHeader: csrfToken(ctx)})
+	return string(headers)
+}
@@ -0,0 +1,34 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/a-h/templ"
+
+	"github.com/mineroot/news/internal/route"
+)
+
+type staticKey struct{}
+
+// WithStatic marks ctx for static site rendering, components skip search and post editing UI
+func WithStatic(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staticKey{}, true)
+}
+
+func isStatic(ctx context.Context) bool {
+	static, _ := ctx.Value(staticKey{}).(bool)
+	return static
+}
+
+// pageUrl links to page of home or search results, static site has a file per home page, so page is passed to url
+func pageUrl(ctx context.Context, url UrlGenerator, r string, page int, search string) templ.SafeURL {
+	if isStatic(ctx) {
+		return templ.URL(url(route.ViewHome, page))
+	}
+	searchQuery := ""
+	if search != "" {
+		searchQuery = "&q=" + search
+	}
+	return templ.URL(url(r) + fmt.Sprintf("?page=%d%s", page, searchQuery))
+}